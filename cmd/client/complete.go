@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+)
+
+// clientCommands are completed as the first word of a line
+var clientCommands = []string{"accept", "ally", "break", "help", "history", "move", "propose", "quit", "say", "spawn", "stats", "status", "whisper"}
+
+// completeClient lists the words that can follow the ones already typed, from
+// our units and the players we know about
+func completeClient(words []string, me gamelogic.Player, ranks []string, players []string) []string {
+	if len(words) == 0 {
+		return clientCommands
+	}
+	switch words[0] {
+	case "spawn":
+		switch len(words) {
+		case 1:
+			return locationNames()
+		case 2:
+			return ranks
+		}
+	case "move":
+		if len(words) == 1 {
+			return locationNames()
+		}
+		typed := map[string]bool{}
+		for _, w := range words[2:] {
+			typed[w] = true
+		}
+		ids := []string{}
+		for _, unit := range sortedUnits(me) {
+			id := strconv.Itoa(unit.ID)
+			if !typed[id] && !unit.InTransit() {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	case "propose", "accept", "break", "whisper", "stats":
+		if len(words) == 1 {
+			return players
+		}
+	}
+	return nil
+}
+
+// gameCompleter completes the prompt of the line by line mode
+func gameCompleter(gs *gamelogic.GameState) func(words []string) []string {
+	return func(words []string) []string {
+		return completeClient(words, gs.GetPlayerSnap(), rankNames(gs.GetRuleset()), gs.GetAllies())
+	}
+}
+
+func rankNames(rules gamelogic.Ruleset) []string {
+	ranks := []string{}
+	for _, rank := range rules.RankNames() {
+		ranks = append(ranks, string(rank))
+	}
+	return ranks
+}
+
+func locationNames() []string {
+	names := []string{}
+	for _, loc := range gamelogic.AllLocations() {
+		names = append(names, string(loc))
+	}
+	return names
+}
+
+func sortedUnits(p gamelogic.Player) []gamelogic.Unit {
+	units := []gamelogic.Unit{}
+	for _, unit := range p.Units {
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].ID < units[j].ID })
+	return units
+}
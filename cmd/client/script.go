@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+)
+
+const defaultExpectTimeout = 30 * time.Second
+
+// input is where commands come from, the terminal, the TUI or a script file
+type input struct {
+	script *bufio.Scanner
+	tui    *tui
+}
+
+func newInput(path string) (*input, error) {
+	if path == "" {
+		return &input{}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open script: %v", err)
+	}
+	return &input{script: bufio.NewScanner(f)}, nil
+}
+
+func (in *input) scripted() bool {
+	return in.script != nil
+}
+
+// next returns the words of the next command, nil once a script is over.
+// Blank lines and lines starting with # are skipped in scripts.
+func (in *input) next() []string {
+	if in.tui != nil {
+		return in.tui.readLine()
+	}
+	if in.script == nil {
+		return gamelogic.GetInput()
+	}
+	for in.script.Scan() {
+		line := strings.TrimSpace(in.script.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Printf("> %s\n", line)
+		return strings.Fields(line)
+	}
+	return nil
+}
+
+// record is one line of the -json output
+type record struct {
+	Type    string
+	Time    time.Time
+	Event   string `json:",omitempty"`
+	Command string `json:",omitempty"`
+	Error   string `json:",omitempty"`
+	Data    any    `json:",omitempty"`
+}
+
+// reporter keeps track of the events we received so scripts can expect them,
+// and writes them out as JSON lines when asked to
+type reporter struct {
+	mu   sync.Mutex
+	out  io.Writer
+	seen map[string]int
+	// expected counts the events of each type already claimed by an expect
+	expected map[string]int
+	changed  chan struct{}
+	// watch is told about every event, the TUI keeps track of the other
+	// players with it
+	watch func(name string, data any)
+}
+
+func newReporter(jsonOut io.Writer) *reporter {
+	return &reporter{
+		out:      jsonOut,
+		seen:     map[string]int{},
+		expected: map[string]int{},
+		changed:  make(chan struct{}),
+	}
+}
+
+// write must be called with the lock held
+func (r *reporter) write(rec record) {
+	if r.out == nil {
+		return
+	}
+	rec.Time = time.Now()
+	data, err := json.Marshal(rec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode a %s record\n", rec.Type)
+		return
+	}
+	fmt.Fprintln(r.out, string(data))
+}
+
+func (r *reporter) event(name string, data any) {
+	r.mu.Lock()
+	r.seen[name]++
+	close(r.changed)
+	r.changed = make(chan struct{})
+	r.write(record{Type: "event", Event: name, Data: data})
+	watch := r.watch
+	r.mu.Unlock()
+	if watch != nil {
+		watch(name, data)
+	}
+}
+
+// result reports how a command went, printing the error for humans
+func (r *reporter) result(words []string, data any, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := record{Type: "result", Command: strings.Join(words, " "), Data: data}
+	if err != nil {
+		fmt.Println(err)
+		rec.Error = err.Error()
+	}
+	r.write(rec)
+}
+
+// expect waits for an event nobody expected yet, events received before the
+// call count so a script can't miss one that came early
+func (r *reporter) expect(name string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		r.mu.Lock()
+		if r.seen[name] > r.expected[name] {
+			r.expected[name]++
+			r.mu.Unlock()
+			return nil
+		}
+		changed := r.changed
+		r.mu.Unlock()
+		select {
+		case <-changed:
+		case <-deadline:
+			return fmt.Errorf("error: no %s event after %v", name, timeout)
+		}
+	}
+}
+
+// recorded reports the messages a handler dealt with, the ones it requeued or
+// discarded were not meant for us
+func recorded[T any](rep *reporter, name string, handler func(T) pubsub.AckType) func(T) pubsub.AckType {
+	return func(msg T) pubsub.AckType {
+		ack := handler(msg)
+		if ack == pubsub.Ack {
+			rep.event(name, msg)
+		}
+		return ack
+	}
+}
+
+// scriptCommand runs the wait and expect directives
+func scriptCommand(rep *reporter, words []string) error {
+	switch words[0] {
+	case "wait":
+		if len(words) < 2 {
+			return fmt.Errorf("usage: wait <duration>")
+		}
+		d, err := time.ParseDuration(words[1])
+		if err != nil {
+			return fmt.Errorf("error: %s is not a valid duration", words[1])
+		}
+		time.Sleep(d)
+		return nil
+	case "expect":
+		if len(words) < 2 {
+			return fmt.Errorf("usage: expect <event> [timeout]")
+		}
+		timeout := defaultExpectTimeout
+		if len(words) > 2 {
+			d, err := time.ParseDuration(words[2])
+			if err != nil {
+				return fmt.Errorf("error: %s is not a valid duration", words[2])
+			}
+			timeout = d
+		}
+		return rep.expect(words[1], timeout)
+	}
+	return fmt.Errorf("error: unknown directive %s", words[0])
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/lineedit"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const maxEvents = 500
+
+// view is what the TUI shows of the game state, taken before locking the TUI
+// since the game state prints to it while holding its own lock
+type view struct {
+	me        gamelogic.Player
+	game      string
+	allies    map[string]bool
+	ranks     []string
+	paused    bool
+	reason    string
+	resumeAt  time.Time
+	turnBased bool
+	turn      int
+	deadline  time.Time
+	funds     int
+	muted     bool
+}
+
+func (t *tui) view() view {
+	v := view{
+		me:        t.gs.GetPlayerSnap(),
+		game:      t.gs.GetGame(),
+		allies:    map[string]bool{},
+		paused:    t.gs.IsPaused(),
+		turnBased: t.gs.IsTurnBased(),
+		funds:     t.gs.GetFunds(),
+		muted:     t.gs.IsMuted(),
+	}
+	for _, ally := range t.gs.GetAllies() {
+		v.allies[ally] = true
+	}
+	v.ranks = rankNames(t.gs.GetRuleset())
+	v.reason, v.resumeAt = t.gs.GetPauseInfo()
+	v.turn, v.deadline = t.gs.GetTurn()
+	return v
+}
+
+// tui splits the terminal in a world pane, an event feed, a status bar and
+// the command line, so what the game prints never runs over what we type
+type tui struct {
+	gs   *gamelogic.GameState
+	conn *amqp.Connection
+
+	mu      sync.Mutex
+	term    *os.File
+	restore func()
+	width   int
+	height  int
+	started bool
+	editor  lineedit.Editor
+	events  []string
+	// scroll is how many lines the feed is scrolled back
+	scroll int
+	// hint shows the completions of the last tab until the next key
+	hint    string
+	enemies map[string]gamelogic.Player
+	lost    bool
+
+	lines   chan string
+	dirty   chan struct{}
+	stopped chan struct{}
+	stdout  *os.File
+}
+
+func newTUI(gs *gamelogic.GameState, conn *amqp.Connection, historyPath string) *tui {
+	t := &tui{
+		gs:      gs,
+		conn:    conn,
+		enemies: map[string]gamelogic.Player{},
+		lines:   make(chan string, 16),
+		dirty:   make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	if historyPath != "" {
+		err := t.editor.LoadHistory(historyPath)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+	return t
+}
+
+// start puts the terminal in raw mode and takes over the screen, everything
+// printed from now on lands in the event feed
+func (t *tui) start() error {
+	restore, err := lineedit.MakeRaw(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("error: the terminal can't be put in raw mode: %v", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		restore()
+		return err
+	}
+
+	t.mu.Lock()
+	t.restore = restore
+	t.term = os.Stdout
+	t.stdout = w
+	t.started = true
+	t.resize()
+	t.mu.Unlock()
+	os.Stdout = w
+	t.gs.SetOutput(t)
+	fmt.Fprint(t.term, "\x1b[?1049h")
+
+	go t.capture(r)
+	go t.readKeys()
+	go t.draw()
+	go t.watch()
+	t.redraw()
+	return nil
+}
+
+// stop gives the terminal back the way we found it
+func (t *tui) stop() {
+	t.mu.Lock()
+	if !t.started {
+		t.mu.Unlock()
+		return
+	}
+	t.started = false
+	close(t.stopped)
+	os.Stdout = t.term
+	t.stdout.Close()
+	fmt.Fprint(t.term, "\x1b[?25h\x1b[?1049l")
+	t.restore()
+	// the last things the game said stay on screen, like why we were kicked
+	for _, event := range t.events[max(len(t.events)-5, 0):] {
+		fmt.Fprintln(t.term, event)
+	}
+	t.mu.Unlock()
+	t.gs.SetOutput(t.term)
+}
+
+// resize must be called with the lock held
+func (t *tui) resize() {
+	t.width, t.height = 80, 24
+	cols, rows, err := lineedit.Size(os.Stdin)
+	if err == nil && rows > 0 && cols > 0 {
+		t.width, t.height = cols, rows
+	}
+}
+
+// readLine waits for the next command, it is what the main loop reads
+// instead of the terminal
+func (t *tui) readLine() []string {
+	select {
+	case line := <-t.lines:
+		return strings.Fields(line)
+	case <-t.stopped:
+		return nil
+	}
+}
+
+// Write takes what the game state prints
+func (t *tui) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		t.addEvent(line)
+	}
+	return len(p), nil
+}
+
+func (t *tui) capture(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		t.addEvent(scanner.Text())
+	}
+}
+
+// addEvent appends a line to the feed, the prompts the handlers print for the
+// line-by-line mode are dropped
+func (t *tui) addEvent(line string) {
+	for strings.HasPrefix(line, "> ") {
+		line = line[2:]
+	}
+	line = strings.TrimRight(line, " \r")
+	if line == "" || line == ">" {
+		return
+	}
+	t.mu.Lock()
+	t.events = append(t.events, line)
+	if len(t.events) > maxEvents {
+		t.events = t.events[len(t.events)-maxEvents:]
+	}
+	if t.scroll > 0 {
+		t.scroll++
+	}
+	t.mu.Unlock()
+	t.redraw()
+}
+
+// event keeps track of the other players from the messages we received
+func (t *tui) event(name string, data any) {
+	username := t.gs.GetUsername()
+	t.mu.Lock()
+	switch msg := data.(type) {
+	case gamelogic.ArmyMove:
+		t.seen(username, msg.Player)
+	case gamelogic.ArmyArrival:
+		t.seen(username, msg.Player)
+	case gamelogic.WarResult:
+		for username, ids := range msg.Casualties {
+			enemy, ok := t.enemies[username]
+			if !ok {
+				continue
+			}
+			units := map[int]gamelogic.Unit{}
+			for id, unit := range enemy.Units {
+				units[id] = unit
+			}
+			for _, id := range ids {
+				delete(units, id)
+			}
+			enemy.Units = units
+			t.enemies[username] = enemy
+		}
+	}
+	t.mu.Unlock()
+	t.redraw()
+}
+
+// seen must be called with the lock held
+func (t *tui) seen(username string, p gamelogic.Player) {
+	if p.Username == username {
+		return
+	}
+	t.enemies[p.Username] = p
+}
+
+// watch notices when the broker goes away and when the terminal is resized
+func (t *tui) watch() {
+	closed := t.conn.NotifyClose(make(chan *amqp.Error, 1))
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stopped:
+			return
+		case err, ok := <-closed:
+			t.mu.Lock()
+			t.lost = true
+			t.mu.Unlock()
+			if ok && err != nil {
+				t.addEvent(fmt.Sprintf("Lost the connection to the broker: %v", err))
+			}
+			closed = nil
+		case <-winch:
+			t.mu.Lock()
+			t.resize()
+			t.mu.Unlock()
+		case <-ticker.C:
+			// countdowns in the status bar
+		}
+		t.redraw()
+	}
+}
+
+func (t *tui) redraw() {
+	select {
+	case t.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (t *tui) draw() {
+	for {
+		select {
+		case <-t.stopped:
+			return
+		case <-t.dirty:
+		}
+		v := t.view()
+		t.mu.Lock()
+		if t.started {
+			fmt.Fprint(t.term, t.render(v))
+		}
+		t.mu.Unlock()
+	}
+}
+
+// readKeys edits the command line
+func (t *tui) readKeys() {
+	in := bufio.NewReader(os.Stdin)
+	for {
+		k, r, err := lineedit.ReadKey(in)
+		if err != nil {
+			t.submit("quit")
+			return
+		}
+		v := t.view()
+		t.mu.Lock()
+		t.hint = ""
+		line, submitted := t.key(k, r, v)
+		t.mu.Unlock()
+		if submitted {
+			t.submit(line)
+		}
+		t.redraw()
+	}
+}
+
+// key must be called with the lock held
+func (t *tui) key(k lineedit.Key, r rune, v view) (string, bool) {
+	page := max(t.height-4, 1)
+	switch k {
+	case lineedit.KeyEnter:
+		return t.editor.Submit(), true
+	case lineedit.KeyInterrupt:
+		return "quit", true
+	case lineedit.KeyEOF:
+		if t.editor.Empty() {
+			return "quit", true
+		}
+		t.editor.Delete()
+	case lineedit.KeyTab:
+		if matches := t.editor.Complete(func(words []string) []string {
+			return completeClient(words, v.me, v.ranks, t.playerNames(v))
+		}); len(matches) > 0 {
+			t.hint = strings.Join(matches, " ")
+		}
+	case lineedit.KeyRedraw:
+		fmt.Fprint(t.term, "\x1b[2J")
+	case lineedit.KeyPageUp:
+		t.scroll = min(t.scroll+page, max(len(t.events)-1, 0))
+	case lineedit.KeyPageDown:
+		t.scroll = max(t.scroll-page, 0)
+	default:
+		t.editor.Apply(k, r)
+	}
+	return "", false
+}
+
+func (t *tui) submit(line string) {
+	if line != "" {
+		t.mu.Lock()
+		t.scroll = 0
+		t.mu.Unlock()
+		t.addEvent("» " + line)
+	}
+	select {
+	case t.lines <- line:
+	case <-t.stopped:
+	}
+}
+
+// playerNames must be called with the lock held
+func (t *tui) playerNames(v view) []string {
+	known := map[string]bool{}
+	for name := range t.enemies {
+		known[name] = true
+	}
+	for name := range v.allies {
+		known[name] = true
+	}
+	names := []string{}
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render draws the whole screen, it must be called with the lock held
+func (t *tui) render(v view) string {
+	var b strings.Builder
+	b.WriteString("\x1b[?25l\x1b[H")
+	paneHeight := max(t.height-3, 1)
+	worldWidth := 0
+	if t.width >= 60 {
+		worldWidth = min(max(t.width*2/5, 28), 48)
+	}
+	feedWidth := t.width - worldWidth
+	if worldWidth > 0 {
+		feedWidth--
+	}
+
+	world := t.worldLines(v)
+	feed := t.feedLines(feedWidth, paneHeight)
+	for row := 0; row <= paneHeight; row++ {
+		left, right := "", ""
+		if row == 0 {
+			left = "\x1b[1m" + fit(" World", worldWidth) + "\x1b[0m"
+			right = "\x1b[1m" + fit(" Events", feedWidth) + "\x1b[0m"
+			if t.scroll > 0 {
+				right = "\x1b[1m" + fit(fmt.Sprintf(" Events (%v lines back, page down to return)", t.scroll), feedWidth) + "\x1b[0m"
+			}
+		} else {
+			if row-1 < len(world) {
+				left = world[row-1]
+			}
+			if row-1 < len(feed) {
+				right = feed[row-1]
+			}
+			left = fit(left, worldWidth)
+			right = fit(right, feedWidth)
+		}
+		if worldWidth > 0 {
+			b.WriteString(left + "│")
+		}
+		b.WriteString(right + "\r\n")
+	}
+	b.WriteString("\x1b[7m" + fit(t.statusLine(v), t.width) + "\x1b[0m\r\n")
+
+	prompt := "> "
+	room := max(t.width-len(prompt)-1, 1)
+	cursor := t.editor.Cursor()
+	start := max(cursor-room, 0)
+	line := []rune(t.editor.Line())[start:]
+	if len(line) > room {
+		line = line[:room]
+	}
+	b.WriteString("\x1b[K" + prompt + string(line))
+	fmt.Fprintf(&b, "\x1b[%v;%vH\x1b[?25h", t.height, len(prompt)+cursor-start+1)
+	return b.String()
+}
+
+// worldLines show who holds each region and where our units are
+func (t *tui) worldLines(v view) []string {
+	names := []string{}
+	for name := range t.enemies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, loc := range gamelogic.AllLocations() {
+		holders := []string{}
+		if n := unitsAt(v.me, loc); n > 0 {
+			holders = append(holders, fmt.Sprintf("you %v", n))
+		}
+		for _, name := range names {
+			n := unitsAt(t.enemies[name], loc)
+			if n == 0 {
+				continue
+			}
+			if v.allies[name] {
+				name += "(ally)"
+			}
+			holders = append(holders, fmt.Sprintf("%s %v", name, n))
+		}
+		held := "-"
+		if len(holders) > 0 {
+			held = strings.Join(holders, ", ")
+		}
+		lines = append(lines, fmt.Sprintf(" %-10s %s", loc, held))
+	}
+
+	lines = append(lines, "", " Your units")
+	units := sortedUnits(v.me)
+	if len(units) == 0 {
+		lines = append(lines, "   none yet, spawn some")
+	}
+	for _, unit := range units {
+		where := string(unit.Location)
+		if unit.InTransit() {
+			where = fmt.Sprintf("%s → %s %v", unit.Location, unit.Destination, time.Until(unit.ArrivesAt).Round(time.Second))
+		}
+		lines = append(lines, fmt.Sprintf(" %4v %-9s %s", unit.ID, unit.Rank, where))
+	}
+	return lines
+}
+
+func unitsAt(p gamelogic.Player, loc gamelogic.Location) int {
+	n := 0
+	for _, unit := range p.Units {
+		if unit.Location == loc && !unit.InTransit() {
+			n++
+		}
+	}
+	return n
+}
+
+// feedLines wraps the latest events to the width of the pane
+func (t *tui) feedLines(width, height int) []string {
+	if width < 2 {
+		return nil
+	}
+	lines := []string{}
+	for _, event := range t.events {
+		runes := []rune(event)
+		for len(runes) > width-1 {
+			lines = append(lines, " "+string(runes[:width-1]))
+			runes = runes[width-1:]
+		}
+		lines = append(lines, " "+string(runes))
+	}
+	end := max(len(lines)-t.scroll, 0)
+	return lines[max(end-height, 0):end]
+}
+
+func (t *tui) statusLine(v view) string {
+	parts := []string{fmt.Sprintf(" %s in %s", v.me.Username, v.game)}
+	if v.paused {
+		paused := "PAUSED"
+		if v.reason != "" {
+			paused += ": " + v.reason
+		}
+		if !v.resumeAt.IsZero() {
+			paused += fmt.Sprintf(", resumes in %v", time.Until(v.resumeAt).Round(time.Second))
+		}
+		parts = append(parts, paused)
+	} else {
+		parts = append(parts, "running")
+	}
+	if v.turnBased {
+		parts = append(parts, fmt.Sprintf("turn %v, %v left", v.turn, max(time.Until(v.deadline), 0).Round(time.Second)))
+	}
+	parts = append(parts, fmt.Sprintf("%v gold", v.funds), fmt.Sprintf("%v units", len(v.me.Units)))
+	if v.muted {
+		parts = append(parts, "muted")
+	}
+	if t.lost || t.conn.IsClosed() {
+		parts = append(parts, "broker: disconnected")
+	} else {
+		parts = append(parts, "broker: connected")
+	}
+	if t.hint != "" {
+		parts = append(parts, t.hint)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// fit pads or cuts s to exactly width columns
+func fit(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/config"
+)
+
+func main() {
+	addr := flag.String("addr", ":8082", "address the gateway listens on")
+	secret := flag.String("secret", os.Getenv("PERIL_GATEWAY_SECRET"), "secret the players' tokens are made from, a random one is generated if empty")
+	issue := flag.String("issue", "", "print the token of this username and exit")
+	origins := flag.String("origins", "", "comma separated origins allowed to open a websocket besides the gateway's own, * allows any")
+	loader := config.Flags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cfg.Apply()
+
+	if *issue != "" {
+		if *secret == "" {
+			fmt.Println("error: -issue needs the gateway's -secret")
+			os.Exit(1)
+		}
+		fmt.Println(gate{secret: *secret}.tokenFor(*issue))
+		return
+	}
+
+	fmt.Println("Starting Peril gateway...")
+	if *secret == "" {
+		*secret, err = randomToken()
+		if err != nil {
+			fmt.Println("Failed to generate a secret")
+			panic(err)
+		}
+		fmt.Printf("Issue the players' tokens with -secret %s -issue <username>\n", *secret)
+	}
+	gate := gate{secret: *secret, origins: splitOrigins(*origins)}
+	names := &usernames{taken: map[string]bool{}}
+	http.HandleFunc("/ws", handlerSocket(cfg, gate, names))
+	fmt.Printf("Listening for websockets on ws://%s/ws\n", *addr)
+	err = http.ListenAndServe(*addr, nil)
+	if err != nil {
+		fmt.Println("Failed to serve websockets")
+		panic(err)
+	}
+}
+
+// gate decides who may play through the gateway
+type gate struct {
+	// secret makes every username's token, so a token only logs in as the
+	// player it was issued to
+	secret string
+	// origins are allowed besides the gateway's own, "*" allows any
+	origins []string
+}
+
+// originAllowed keeps other websites from opening a socket from a player's
+// browser. Clients that aren't browsers don't send an Origin at all.
+func (g gate) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range g.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g gate) tokenFor(username string) string {
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write([]byte(username))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (g gate) tokenValid(username, token string) bool {
+	return hmac.Equal([]byte(token), []byte(g.tokenFor(username)))
+}
+
+func splitOrigins(s string) []string {
+	origins := []string{}
+	for _, origin := range strings.Split(s, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// handlerSocket plays a whole game over one websocket
+func handlerSocket(cfg config.Config, gate gate, names *usernames) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !gate.originAllowed(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		ws, err := upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		s := &session{ws: ws}
+
+		msg, err := login(ws, gate)
+		if err != nil {
+			s.sendError(err)
+			return
+		}
+		if !names.claim(msg.Username) {
+			s.sendError(fmt.Errorf("error: %s is already playing through this gateway", msg.Username))
+			return
+		}
+		defer names.release(msg.Username)
+
+		s.conn, err = cfg.Dial()
+		if err != nil {
+			s.sendError(fmt.Errorf("error: could not reach the server"))
+			return
+		}
+		defer s.conn.Close()
+		s.ch, err = s.conn.Channel()
+		if err != nil {
+			s.sendError(fmt.Errorf("error: could not reach the server"))
+			return
+		}
+
+		err = s.start(msg.Username, msg.Game)
+		if err != nil {
+			s.sendError(err)
+			return
+		}
+		fmt.Printf("%s joined %s through the gateway\n", msg.Username, msg.Game)
+		s.run()
+		fmt.Printf("%s left the gateway\n", msg.Username)
+	}
+}
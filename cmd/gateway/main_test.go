@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins string
+		origin  string
+		want    bool
+	}{
+		{"no origin", "", "", true},
+		{"same host", "", "http://peril.example:8082", true},
+		{"other site", "", "https://evil.example", false},
+		{"listed", "https://play.example/", "https://play.example", true},
+		{"listed elsewhere", "https://play.example", "https://evil.example", false},
+		{"any", "*", "https://evil.example", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gate{origins: splitOrigins(tt.origins)}
+			r := httptest.NewRequest("GET", "http://peril.example:8082/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := g.originAllowed(r); got != tt.want {
+				t.Errorf("originAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenValid(t *testing.T) {
+	g := gate{secret: "s3cret"}
+	other := gate{secret: "other"}
+	tests := []struct {
+		name     string
+		username string
+		token    string
+		want     bool
+	}{
+		{"issued", "alice", g.tokenFor("alice"), true},
+		{"someone else's", "alice", g.tokenFor("bob"), false},
+		{"other secret", "alice", other.tokenFor("alice"), false},
+		{"empty", "alice", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.tokenValid(tt.username, tt.token); got != tt.want {
+				t.Errorf("tokenValid(%q, %q) = %v, want %v", tt.username, tt.token, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/player"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const loginTimeout = 30 * time.Second
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// clientMessage is sent by the browser, a login first and then commands
+type clientMessage struct {
+	Type     string
+	Username string
+	// Token is the one issued to Username, only needed to log in
+	Token   string
+	Game    string
+	Command string
+}
+
+// serverMessage is sent to the browser. Text is what the terminal client
+// would print, events carry the raw data for clients that draw their own UI.
+type serverMessage struct {
+	Type  string
+	Event string `json:",omitempty"`
+	Text  string `json:",omitempty"`
+	Error string `json:",omitempty"`
+	Data  any    `json:",omitempty"`
+}
+
+// usernames keeps two sockets of the same gateway from playing as the same
+// player
+type usernames struct {
+	mu    sync.Mutex
+	taken map[string]bool
+}
+
+func (u *usernames) claim(name string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.taken[name] {
+		return false
+	}
+	u.taken[name] = true
+	return true
+}
+
+func (u *usernames) release(name string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.taken, name)
+}
+
+// session is one player connected through a websocket. Like cmd/client, it
+// has its own broker connection and game state.
+type session struct {
+	ws   *wsConn
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	gs   *gamelogic.GameState
+	play *player.Session
+}
+
+func (s *session) send(msg serverMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		fmt.Printf("Failed to encode a %s message\n", msg.Type)
+		return
+	}
+	s.ws.WriteText(data)
+}
+
+func (s *session) sendEvent(event string, data any) {
+	s.send(serverMessage{Type: "event", Event: event, Data: data})
+}
+
+// sent forwards the messages a handler dealt with to the browser
+func sent[T any](s *session, event string, handler func(T) pubsub.AckType) func(T) pubsub.AckType {
+	return func(msg T) pubsub.AckType {
+		ack := handler(msg)
+		if ack == pubsub.Ack {
+			s.sendEvent(event, msg)
+		}
+		return ack
+	}
+}
+
+func (s *session) sendError(err error) {
+	s.send(serverMessage{Type: "error", Error: err.Error()})
+}
+
+// Write lets the game state talk to the browser
+func (s *session) Write(p []byte) (int, error) {
+	text := strings.TrimRight(string(p), "\n")
+	if text != "" {
+		s.send(serverMessage{Type: "text", Text: text})
+	}
+	return len(p), nil
+}
+
+// login reads the first message of the socket, it must name the player, carry
+// the token issued to them and name the game they want to join
+func login(ws *wsConn, gate gate) (clientMessage, error) {
+	ws.conn.SetReadDeadline(time.Now().Add(loginTimeout))
+	defer ws.conn.SetReadDeadline(time.Time{})
+	data, err := ws.ReadMessage()
+	if err != nil {
+		return clientMessage{}, err
+	}
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return clientMessage{}, errors.New("error: could not parse the login")
+	}
+	if msg.Type != "login" {
+		return clientMessage{}, errors.New("error: log in first")
+	}
+	if !usernamePattern.MatchString(msg.Username) {
+		return clientMessage{}, errors.New("error: usernames are 1 to 32 letters, digits, - or _")
+	}
+	if !gate.tokenValid(msg.Username, msg.Token) {
+		return clientMessage{}, errors.New("error: wrong token")
+	}
+	if msg.Game == "" {
+		msg.Game = "main"
+	}
+	return msg, nil
+}
+
+// start joins the game and subscribes to everything the player needs to know
+func (s *session) start(name, game string) error {
+	resp, err := pubsub.RequestJSON[routing.JoinRequest, routing.JoinResponse](s.conn, routing.ExchangePerilDirect, routing.RPCJoinKey, routing.JoinRequest{
+		Game:     game,
+		Username: name,
+	}, routing.RPCTimeout)
+	if err != nil {
+		return errors.New("error: the server did not answer")
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	info := resp.Game
+
+	s.gs = gamelogic.NewGameState(name)
+	s.gs.SetOutput(s)
+	s.gs.SetGame(info.ID)
+	s.gs.SetTurnBased(info.TurnBased)
+	s.gs.SetMuted(resp.Muted)
+	s.play = player.New(s.gs, s.ch, player.Hooks{
+		// the player sees what went wrong too, like the queued orders that failed
+		Logf: func(format string, a ...any) {
+			message := fmt.Sprintf(format, a...)
+			fmt.Printf("[%s] %s\n", name, message)
+			s.send(serverMessage{Type: "error", Error: message})
+		},
+		Fought: func(result gamelogic.WarResult) {
+			s.sendEvent("war", result)
+		},
+	})
+
+	rules, err := pubsub.RequestJSON[struct{}, gamelogic.Ruleset](s.conn, routing.ExchangePerilDirect, routing.RPCRulesetKey, struct{}{}, routing.RPCTimeout)
+	if err == nil && rules.Validate() == nil {
+		s.gs.JoinWith(rules)
+	}
+
+	pauseKey := routing.GameKey(routing.PauseKey, info.ID, name)
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDirect, pauseKey, routing.GameKey(routing.PauseKey, info.ID), pubsub.TransientQueue, s.handlerPause)
+	if err != nil {
+		return err
+	}
+	state, err := pubsub.RequestJSON[routing.StateRequest, routing.StateResponse](s.conn, routing.ExchangePerilDirect, routing.RPCStateKey, routing.StateRequest{Game: info.ID}, routing.RPCTimeout)
+	if err == nil && state.Error == "" {
+		s.gs.SyncPlayingState(state.State)
+	}
+
+	controlKey := fmt.Sprintf("%s.%s", routing.ControlPrefix, name)
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDirect, controlKey, controlKey, pubsub.TransientQueue, s.handlerControl)
+	if err != nil {
+		return err
+	}
+	closedKey := routing.GameKey(routing.GameClosedPrefix, info.ID)
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDirect, routing.GameKey(routing.GameClosedPrefix, info.ID, name), closedKey, pubsub.TransientQueue, s.handlerGameClosed)
+	if err != nil {
+		return err
+	}
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDirect, routing.GameKey(routing.GameOverPrefix, info.ID, name), routing.GameKey(routing.GameOverPrefix, info.ID), pubsub.TransientQueue, s.handlerGameOver)
+	if err != nil {
+		return err
+	}
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDirect, routing.GameKey(routing.IncomePrefix, info.ID, name), routing.GameKey(routing.IncomePrefix, info.ID), pubsub.TransientQueue, sent(s, "income", s.play.HandleIncome))
+	if err != nil {
+		return err
+	}
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDirect, routing.GameKey(routing.TurnStartPrefix, info.ID, name), routing.GameKey(routing.TurnStartPrefix, info.ID), pubsub.TransientQueue, s.handlerTurnStart)
+	if err != nil {
+		return err
+	}
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDirect, routing.GameKey(routing.TurnEndPrefix, info.ID, name), routing.GameKey(routing.TurnEndPrefix, info.ID), pubsub.TransientQueue, sent(s, "turn_end", s.play.HandleTurnEnd))
+	if err != nil {
+		return err
+	}
+	err = pubsub.DeclareExchange(s.conn, routing.ExchangePerilDiplomacy, amqp.ExchangeTopic)
+	if err != nil {
+		return err
+	}
+	diplomacyKey := routing.GameKey(routing.DiplomacyPrefix, info.ID, name)
+	err = pubsub.SubscribeJSON(s.conn, routing.ExchangePerilDiplomacy, diplomacyKey, diplomacyKey, pubsub.TransientQueue, s.handlerDiplomacy)
+	if err != nil {
+		return err
+	}
+
+	err = s.play.SubscribeMoves(s.conn, sent(s, "move", s.play.HandleMove), sent(s, "arrival", s.play.HandleArrival))
+	if err != nil {
+		return err
+	}
+	err = s.play.SubscribeWars(s.conn, s.play.HandleWar, s.play.HandleWarResult)
+	if err != nil {
+		return err
+	}
+
+	s.send(serverMessage{Type: "welcome", Data: info})
+	s.play.PublishStatus()
+	return nil
+}
+
+// run reads the player's commands until the socket closes
+func (s *session) run() {
+	for {
+		data, err := s.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "command" {
+			s.sendError(errors.New("error: expected a command"))
+			continue
+		}
+		words := strings.Fields(strings.ToLower(msg.Command))
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "spawn", "move":
+			if err := s.play.Order(words); err != nil {
+				s.sendError(err)
+			}
+		case "status":
+			s.gs.CommandStatus()
+			s.sendEvent("status", s.gs.GetPlayerSnap())
+		case "propose", "accept", "break":
+			if err := s.runDiplomacy(words); err != nil {
+				s.sendError(err)
+			}
+		default:
+			s.sendError(fmt.Errorf("error: unknown command %s", words[0]))
+		}
+	}
+}
+
+func (s *session) runDiplomacy(words []string) error {
+	var msg gamelogic.DiplomacyMessage
+	var err error
+	switch words[0] {
+	case "propose":
+		msg, err = s.gs.CommandPropose(words)
+	case "accept":
+		msg, err = s.gs.CommandAccept(words)
+	case "break":
+		msg, err = s.gs.CommandBreak(words)
+	}
+	if err != nil {
+		return err
+	}
+	err = pubsub.PublishJSON(s.ch, routing.ExchangePerilDiplomacy, routing.GameKey(routing.DiplomacyPrefix, s.gs.GetGame(), msg.To), msg)
+	if err != nil {
+		return errors.New("error: failed to publish the diplomacy message")
+	}
+	return nil
+}
+
+func (s *session) handlerPause(ps routing.PlayingState) pubsub.AckType {
+	s.gs.HandlePause(ps)
+	s.sendEvent("pause", ps)
+	return pubsub.Ack
+}
+
+func (s *session) handlerControl(msg routing.ControlMessage) pubsub.AckType {
+	s.sendEvent("control", msg)
+	if s.gs.HandleControl(msg) {
+		s.ws.Close()
+	}
+	return pubsub.Ack
+}
+
+func (s *session) handlerGameClosed(gc routing.GameClosed) pubsub.AckType {
+	s.gs.HandleGameClosed(gc)
+	s.sendEvent("game_closed", gc)
+	return pubsub.Ack
+}
+
+func (s *session) handlerGameOver(over gamelogic.GameOver) pubsub.AckType {
+	s.gs.HandleGameOver(over)
+	s.sendEvent("game_over", over)
+	return pubsub.Ack
+}
+
+func (s *session) handlerTurnStart(ts routing.TurnStart) pubsub.AckType {
+	s.gs.HandleTurnStart(ts)
+	s.sendEvent("turn_start", ts)
+	return pubsub.Ack
+}
+
+func (s *session) handlerDiplomacy(msg gamelogic.DiplomacyMessage) pubsub.AckType {
+	s.gs.HandleDiplomacy(msg)
+	if msg.Type != gamelogic.DiplomacyStatus {
+		s.sendEvent("diplomacy", msg)
+	}
+	return pubsub.Ack
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// clientFrame builds a frame the way a browser sends it, masked
+func clientFrame(fin bool, opcode byte, payload []byte, lengthBytes int) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch lengthBytes {
+	case 2:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	case 8:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	default:
+		frame = append(frame, 0x80|byte(len(payload)))
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func TestReadFrame(t *testing.T) {
+	long := bytes.Repeat([]byte("peril"), 100)
+	tests := []struct {
+		name    string
+		frame   []byte
+		fin     bool
+		opcode  byte
+		payload []byte
+		err     error
+	}{
+		{
+			name:    "masked text",
+			frame:   clientFrame(true, opText, []byte(`{"Type":"login"}`), 0),
+			fin:     true,
+			opcode:  opText,
+			payload: []byte(`{"Type":"login"}`),
+		},
+		{
+			name:    "empty",
+			frame:   clientFrame(true, opText, nil, 0),
+			fin:     true,
+			opcode:  opText,
+			payload: []byte{},
+		},
+		{
+			name:    "16 bit length",
+			frame:   clientFrame(true, opBinary, long, 2),
+			fin:     true,
+			opcode:  opBinary,
+			payload: long,
+		},
+		{
+			name:    "64 bit length",
+			frame:   clientFrame(false, opText, long, 8),
+			fin:     false,
+			opcode:  opText,
+			payload: long,
+		},
+		{
+			name:    "ping",
+			frame:   clientFrame(true, opPing, []byte("hi"), 0),
+			fin:     true,
+			opcode:  opPing,
+			payload: []byte("hi"),
+		},
+		{
+			name:    "close",
+			frame:   clientFrame(true, opClose, []byte{0x03, 0xE8}, 0),
+			fin:     true,
+			opcode:  opClose,
+			payload: []byte{0x03, 0xE8},
+		},
+		{
+			name:  "too big",
+			frame: clientFrame(true, opBinary, bytes.Repeat([]byte{1}, maxMessageSize+1), 8),
+			err:   errMessageTooBig,
+		},
+		{
+			name:  "unmasked",
+			frame: []byte{0x81, 0x02, 'h', 'i'},
+			err:   errors.New("client websocket frames must be masked"),
+		},
+		{
+			name:  "extension bits",
+			frame: append([]byte{0xC1}, clientFrame(true, opText, []byte("hi"), 0)[1:]...),
+			err:   errors.New("websocket extensions are not supported"),
+		},
+		{
+			name:  "long ping",
+			frame: clientFrame(true, opPing, bytes.Repeat([]byte{1}, maxControlSize+1), 2),
+			err:   errors.New("websocket control frame too big"),
+		},
+		{
+			name:  "fragmented close",
+			frame: clientFrame(false, opClose, []byte{0x03, 0xE8}, 0),
+			err:   errors.New("websocket control frames can't be fragmented"),
+		},
+		{
+			name:  "cut short",
+			frame: clientFrame(true, opText, []byte("hello"), 0)[:8],
+			err:   io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &wsConn{r: bufio.NewReader(bytes.NewReader(tt.frame))}
+			fin, opcode, payload, err := c.readFrame()
+			if tt.err != nil {
+				if err == nil || (!errors.Is(err, tt.err) && err.Error() != tt.err.Error()) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fin != tt.fin || opcode != tt.opcode {
+				t.Errorf("got fin %v opcode %v, want fin %v opcode %v", fin, opcode, tt.fin, tt.opcode)
+			}
+			if !bytes.Equal(payload, tt.payload) {
+				t.Errorf("got payload %q, want %q", payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	c := &wsConn{conn: server, r: bufio.NewReader(server)}
+
+	go func() {
+		// a message split in two with a ping in the middle, answered with a pong
+		client.Write(clientFrame(false, opText, []byte("hel"), 0))
+		client.Write(clientFrame(true, opPing, []byte("?"), 0))
+		pong := make([]byte, 3)
+		io.ReadFull(client, pong)
+		client.Write(clientFrame(true, opContinuation, []byte("lo"), 0))
+	}()
+	msg, err := c.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != "hello" {
+		t.Errorf("got %q, want %q", msg, "hello")
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/config"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/player"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// idleThinks is how many think delays a bot waits for something to happen
+// before it acts anyway
+const idleThinks = 5
+
+type botConfig struct {
+	broker   config.Config
+	game     string
+	strategy strategy
+	think    time.Duration
+	maxUnits int
+	verbose  bool
+}
+
+// bot plays one player. Like cmd/client it has its own broker connection and
+// game state, but a strategy gives the orders.
+type bot struct {
+	name string
+	cfg  botConfig
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	gs   *gamelogic.GameState
+	play *player.Session
+
+	mu sync.Mutex
+	// rng is only used under mu, so the same seed gives the same decisions
+	rng     *rand.Rand
+	enemies map[string]gamelogic.Player
+	over    bool
+	// wake is poked by every event worth reacting to
+	wake chan struct{}
+}
+
+func newBot(name string, cfg botConfig, seed int64) *bot {
+	return &bot{
+		name:    name,
+		cfg:     cfg,
+		rng:     rand.New(rand.NewSource(seed)),
+		enemies: map[string]gamelogic.Player{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (b *bot) logf(format string, a ...any) {
+	fmt.Printf("[%s] %s\n", b.name, fmt.Sprintf(format, a...))
+}
+
+// Write shows what the game tells the bot when running verbose
+func (b *bot) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			b.logf("%s", line)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *bot) poke() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// start connects the bot and joins the game
+func (b *bot) start() error {
+	var err error
+	b.conn, err = b.cfg.broker.Dial()
+	if err != nil {
+		return fmt.Errorf("could not connect to RabbitMQ: %v", err)
+	}
+	b.ch, err = b.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("could not open a channel: %v", err)
+	}
+
+	resp, err := pubsub.RequestJSON[routing.JoinRequest, routing.JoinResponse](b.conn, routing.ExchangePerilDirect, routing.RPCJoinKey, routing.JoinRequest{
+		Game:     b.cfg.game,
+		Username: b.name,
+	}, routing.RPCTimeout)
+	if err != nil {
+		return fmt.Errorf("could not join %s: %v", b.cfg.game, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("could not join %s: %s", b.cfg.game, resp.Error)
+	}
+	game := resp.Game
+
+	b.gs = gamelogic.NewGameState(b.name)
+	b.gs.SetOutput(io.Discard)
+	if b.cfg.verbose {
+		b.gs.SetOutput(b)
+	}
+	b.gs.SetGame(game.ID)
+	b.gs.SetTurnBased(game.TurnBased)
+	b.gs.SetMuted(resp.Muted)
+	rules, err := pubsub.RequestJSON[struct{}, gamelogic.Ruleset](b.conn, routing.ExchangePerilDirect, routing.RPCRulesetKey, struct{}{}, routing.RPCTimeout)
+	if err == nil && rules.Validate() == nil {
+		b.gs.JoinWith(rules)
+	}
+	b.play = player.New(b.gs, b.ch, player.Hooks{
+		Logf:    b.logf,
+		Seen:    b.seeEnemy,
+		Arrived: func(gamelogic.ArmyArrival) { b.poke() },
+		Fought:  b.fought,
+		Seed: func() int64 {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return b.rng.Int63()
+		},
+	})
+
+	err = pubsub.SubscribeJSON(b.conn, routing.ExchangePerilDirect, routing.GameKey(routing.PauseKey, game.ID, b.name), routing.GameKey(routing.PauseKey, game.ID), pubsub.TransientQueue, b.handlerPause)
+	if err != nil {
+		return err
+	}
+	state, err := pubsub.RequestJSON[routing.StateRequest, routing.StateResponse](b.conn, routing.ExchangePerilDirect, routing.RPCStateKey, routing.StateRequest{Game: game.ID}, routing.RPCTimeout)
+	if err == nil && state.Error == "" {
+		b.gs.SyncPlayingState(state.State)
+	}
+
+	subscriptions := []error{
+		pubsub.SubscribeJSON(b.conn, routing.ExchangePerilDirect, routing.GameKey(routing.GameClosedPrefix, game.ID, b.name), routing.GameKey(routing.GameClosedPrefix, game.ID), pubsub.TransientQueue, b.handlerGameClosed),
+		pubsub.SubscribeJSON(b.conn, routing.ExchangePerilDirect, routing.GameKey(routing.GameOverPrefix, game.ID, b.name), routing.GameKey(routing.GameOverPrefix, game.ID), pubsub.TransientQueue, b.handlerGameOver),
+		pubsub.SubscribeJSON(b.conn, routing.ExchangePerilDirect, routing.GameKey(routing.IncomePrefix, game.ID, b.name), routing.GameKey(routing.IncomePrefix, game.ID), pubsub.TransientQueue, b.play.HandleIncome),
+		pubsub.SubscribeJSON(b.conn, routing.ExchangePerilDirect, routing.GameKey(routing.TurnStartPrefix, game.ID, b.name), routing.GameKey(routing.TurnStartPrefix, game.ID), pubsub.TransientQueue, b.handlerTurnStart),
+		pubsub.SubscribeJSON(b.conn, routing.ExchangePerilDirect, routing.GameKey(routing.TurnEndPrefix, game.ID, b.name), routing.GameKey(routing.TurnEndPrefix, game.ID), pubsub.TransientQueue, b.play.HandleTurnEnd),
+		b.play.SubscribeMoves(b.conn, poked(b, b.play.HandleMove), poked(b, b.play.HandleArrival)),
+		b.play.SubscribeWars(b.conn, b.play.HandleWar, b.handlerWarResult),
+	}
+	for _, err := range subscriptions {
+		if err != nil {
+			return err
+		}
+	}
+
+	b.play.PublishStatus()
+	b.logf("joined %s playing %s", game.ID, b.cfg.strategy.Name())
+	return nil
+}
+
+// run thinks whenever something happens, or every few think delays when
+// nothing does, until the game is over or stop is closed
+func (b *bot) run(stop <-chan struct{}) {
+	defer b.conn.Close()
+	idle := time.NewTimer(b.cfg.think * idleThinks)
+	defer idle.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-b.wake:
+		case <-idle.C:
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(b.thinkDelay()):
+		}
+		if b.isOver() {
+			b.logf("the game is over, leaving")
+			return
+		}
+		for _, order := range b.decide() {
+			b.order(order)
+		}
+		idle.Reset(b.cfg.think * idleThinks)
+	}
+}
+
+// thinkDelay is the think delay give or take half of it
+func (b *bot) thinkDelay() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.think <= 0 {
+		return 0
+	}
+	return b.cfg.think/2 + time.Duration(b.rng.Int63n(int64(b.cfg.think)))
+}
+
+func (b *bot) isOver() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.over
+}
+
+func (b *bot) decide() [][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v := view{
+		Me:    b.gs.GetPlayerSnap(),
+		Funds: b.gs.GetFunds(),
+		Rules: b.gs.GetRuleset(),
+	}
+	for _, enemy := range b.enemies {
+		v.Enemies = append(v.Enemies, enemy)
+	}
+	sort.Slice(v.Enemies, func(i, j int) bool { return v.Enemies[i].Username < v.Enemies[j].Username })
+
+	orders := [][]string{}
+	for _, order := range b.cfg.strategy.Act(v, b.rng) {
+		if order[0] == "spawn" && len(v.Me.Units) >= b.cfg.maxUnits {
+			continue
+		}
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+func (b *bot) order(words []string) {
+	b.logf("%s", strings.Join(words, " "))
+	if err := b.play.Order(words); err != nil {
+		b.logf("%v", err)
+	}
+}
+
+// seeEnemy remembers the latest snapshot of another player
+func (b *bot) seeEnemy(p gamelogic.Player) {
+	if p.Username == b.name || b.gs.IsAlly(p.Username) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.enemies[p.Username] = p
+}
+
+func (b *bot) handlerPause(ps routing.PlayingState) pubsub.AckType {
+	b.gs.HandlePause(ps)
+	return pubsub.Ack
+}
+
+func (b *bot) handlerGameClosed(gc routing.GameClosed) pubsub.AckType {
+	b.gs.HandleGameClosed(gc)
+	b.mu.Lock()
+	b.over = true
+	b.mu.Unlock()
+	b.poke()
+	return pubsub.Ack
+}
+
+func (b *bot) handlerGameOver(over gamelogic.GameOver) pubsub.AckType {
+	b.gs.HandleGameOver(over)
+	b.logf("game over, %s won", over.Winner)
+	b.mu.Lock()
+	b.over = true
+	b.mu.Unlock()
+	b.poke()
+	return pubsub.Ack
+}
+
+func (b *bot) handlerTurnStart(ts routing.TurnStart) pubsub.AckType {
+	b.gs.HandleTurnStart(ts)
+	b.poke()
+	return pubsub.Ack
+}
+
+// poked wakes the bot up after the moves it could react to
+func poked[T any](b *bot, handler func(T) pubsub.AckType) func(T) pubsub.AckType {
+	return func(msg T) pubsub.AckType {
+		defer b.poke()
+		return handler(msg)
+	}
+}
+
+// fought reports the wars the bot resolved itself, the others are handled by
+// handlerWarResult
+func (b *bot) fought(result gamelogic.WarResult) {
+	if result.ResolvedBy != b.name {
+		return
+	}
+	b.logf("fought %s in %s: %s", result.Defender, result.Location, describeOutcome(result))
+	b.forgetCasualties(result)
+	b.poke()
+}
+
+// handlerWarResult also keeps track of the enemies killed in wars we were not
+// part of
+func (b *bot) handlerWarResult(result gamelogic.WarResult) pubsub.AckType {
+	ack := b.play.HandleWarResult(result)
+	b.forgetCasualties(result)
+	b.poke()
+	return ack
+}
+
+// forgetCasualties removes the enemy units we know died from our snapshots
+func (b *bot) forgetCasualties(result gamelogic.WarResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for username, ids := range result.Casualties {
+		enemy, ok := b.enemies[username]
+		if !ok {
+			continue
+		}
+		units := map[int]gamelogic.Unit{}
+		for id, unit := range enemy.Units {
+			units[id] = unit
+		}
+		for _, id := range ids {
+			delete(units, id)
+		}
+		enemy.Units = units
+		b.enemies[username] = enemy
+	}
+}
+
+func describeOutcome(result gamelogic.WarResult) string {
+	if result.Draw {
+		return "draw"
+	}
+	return fmt.Sprintf("%s won", result.Winner)
+}
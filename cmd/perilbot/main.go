@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/config"
+)
+
+func main() {
+	count := flag.Int("bots", 1, "number of bots to run")
+	strategyName := flag.String("strategy", "random", "random, aggressive, defensive or greedy")
+	game := flag.String("game", "main", "game the bots join")
+	prefix := flag.String("prefix", "bot", "bots are named <prefix>-<n>")
+	think := flag.Duration("think", 2*time.Second, "how long a bot thinks before acting, give or take half")
+	maxUnits := flag.Int("max-units", 20, "bots stop spawning past this many units")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the bots' decisions")
+	verbose := flag.Bool("verbose", false, "show what the game tells the bots")
+	loader := config.Flags(flag.CommandLine)
+	flag.Parse()
+
+	broker, err := loader.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	broker.Apply()
+
+	strat, err := getStrategy(*strategyName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cfg := botConfig{
+		broker:   broker,
+		game:     *game,
+		strategy: strat,
+		think:    *think,
+		maxUnits: *maxUnits,
+		verbose:  *verbose,
+	}
+	fmt.Printf("Starting %v %s bot(s) in %s with seed %v\n", *count, strat.Name(), *game, *seed)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 1; i <= *count; i++ {
+		b := newBot(fmt.Sprintf("%s-%d", *prefix, i), cfg, *seed+int64(i))
+		if err := b.start(); err != nil {
+			fmt.Printf("Failed to start %s: %v\n", b.name, err)
+			if b.conn != nil {
+				b.conn.Close()
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.run(stop)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-sig:
+		fmt.Println("Stopping the bots...")
+		close(stop)
+		<-done
+	case <-done:
+	}
+}
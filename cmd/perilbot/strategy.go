@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+)
+
+// view is everything a bot knows when it thinks. Enemies are the last
+// snapshots seen in their moves, so they can be out of date.
+type view struct {
+	Me      gamelogic.Player
+	Enemies []gamelogic.Player
+	Funds   int
+	Rules   gamelogic.Ruleset
+}
+
+// strategy decides what a bot does. Orders are the same words a player types
+// in the client, and must only depend on the view and rng so that a seed
+// replays the same decisions.
+type strategy interface {
+	Name() string
+	Act(v view, rng *rand.Rand) [][]string
+}
+
+func getStrategy(name string) (strategy, error) {
+	switch name {
+	case "random":
+		return randomStrategy{}, nil
+	case "aggressive":
+		return aggressiveStrategy{}, nil
+	case "defensive":
+		return defensiveStrategy{}, nil
+	case "greedy":
+		return greedyStrategy{}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %s, use random, aggressive, defensive or greedy", name)
+}
+
+// randomStrategy spawns or moves at random
+type randomStrategy struct{}
+
+func (randomStrategy) Name() string { return "random" }
+
+func (randomStrategy) Act(v view, rng *rand.Rand) [][]string {
+	locations := gamelogic.AllLocations()
+	ranks := affordableRanks(v.Rules, v.Funds)
+	idle := idleUnits(v.Me)
+	if len(ranks) > 0 && (len(idle) == 0 || rng.Intn(2) == 0) {
+		return [][]string{spawnOrder(locations[rng.Intn(len(locations))], ranks[rng.Intn(len(ranks))])}
+	}
+	if len(idle) == 0 {
+		return nil
+	}
+	unit := idle[rng.Intn(len(idle))]
+	to := locations[rng.Intn(len(locations))]
+	if to == unit.Location {
+		return nil
+	}
+	return [][]string{moveOrder(to, []gamelogic.Unit{unit})}
+}
+
+// aggressiveStrategy fields as many units as it can and throws all of them
+// at the biggest enemy army
+type aggressiveStrategy struct{}
+
+func (aggressiveStrategy) Name() string { return "aggressive" }
+
+func (aggressiveStrategy) Act(v view, rng *rand.Rand) [][]string {
+	orders := [][]string{}
+	if rank, ok := cheapestRank(v.Rules, v.Funds); ok {
+		orders = append(orders, spawnOrder(home(v.Me, rng), rank))
+	}
+	target, ok := mostEnemies(v.Enemies)
+	if !ok {
+		return orders
+	}
+	if units := unitsNotAt(idleUnits(v.Me), target); len(units) > 0 {
+		orders = append(orders, moveOrder(target, units))
+	}
+	return orders
+}
+
+// defensiveStrategy never attacks, it keeps its army together at home and
+// reinforces it with the strongest units it can afford
+type defensiveStrategy struct{}
+
+func (defensiveStrategy) Name() string { return "defensive" }
+
+func (defensiveStrategy) Act(v view, rng *rand.Rand) [][]string {
+	orders := [][]string{}
+	base := home(v.Me, rng)
+	if rank, ok := strongestRank(v.Rules, v.Funds); ok {
+		orders = append(orders, spawnOrder(base, rank))
+	}
+	if units := unitsNotAt(idleUnits(v.Me), base); len(units) > 0 {
+		orders = append(orders, moveOrder(base, units))
+	}
+	return orders
+}
+
+// greedyStrategy buys the most power for its gold and only attacks where its
+// whole army outpowers the enemy
+type greedyStrategy struct{}
+
+func (greedyStrategy) Name() string { return "greedy" }
+
+func (greedyStrategy) Act(v view, rng *rand.Rand) [][]string {
+	orders := [][]string{}
+	if rank, ok := bestValueRank(v.Rules, v.Funds); ok {
+		orders = append(orders, spawnOrder(home(v.Me, rng), rank))
+	}
+	idle := idleUnits(v.Me)
+	var target gamelogic.Location
+	bestMargin := 0
+	for _, loc := range gamelogic.AllLocations() {
+		enemies := enemyUnitsAt(v.Enemies, loc)
+		if len(enemies) == 0 {
+			continue
+		}
+		margin := v.Rules.PowerLevel(idle, enemies) - v.Rules.PowerLevel(enemies, idle)
+		if margin > bestMargin {
+			target, bestMargin = loc, margin
+		}
+	}
+	if target == "" {
+		return orders
+	}
+	if units := unitsNotAt(idle, target); len(units) > 0 {
+		orders = append(orders, moveOrder(target, units))
+	}
+	return orders
+}
+
+func spawnOrder(loc gamelogic.Location, rank gamelogic.UnitRank) []string {
+	return []string{"spawn", string(loc), string(rank)}
+}
+
+func moveOrder(to gamelogic.Location, units []gamelogic.Unit) []string {
+	order := []string{"move", string(to)}
+	for _, unit := range units {
+		order = append(order, strconv.Itoa(unit.ID))
+	}
+	return order
+}
+
+// idleUnits returns the units that are not travelling, sorted by ID
+func idleUnits(p gamelogic.Player) []gamelogic.Unit {
+	units := []gamelogic.Unit{}
+	for _, unit := range p.Units {
+		if !unit.InTransit() {
+			units = append(units, unit)
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].ID < units[j].ID })
+	return units
+}
+
+func unitsNotAt(units []gamelogic.Unit, loc gamelogic.Location) []gamelogic.Unit {
+	away := []gamelogic.Unit{}
+	for _, unit := range units {
+		if unit.Location != loc {
+			away = append(away, unit)
+		}
+	}
+	return away
+}
+
+func enemyUnitsAt(enemies []gamelogic.Player, loc gamelogic.Location) []gamelogic.Unit {
+	units := []gamelogic.Unit{}
+	for _, enemy := range enemies {
+		for _, unit := range enemy.Units {
+			if unit.Location == loc && !unit.InTransit() {
+				units = append(units, unit)
+			}
+		}
+	}
+	return units
+}
+
+// home is where most of the player's units are, or a random location for a
+// player with no units yet
+func home(p gamelogic.Player, rng *rand.Rand) gamelogic.Location {
+	counts := map[gamelogic.Location]int{}
+	for _, unit := range p.Units {
+		counts[unit.Location]++
+	}
+	var best gamelogic.Location
+	for _, loc := range gamelogic.AllLocations() {
+		if counts[loc] > counts[best] {
+			best = loc
+		}
+	}
+	if best == "" {
+		locations := gamelogic.AllLocations()
+		return locations[rng.Intn(len(locations))]
+	}
+	return best
+}
+
+func mostEnemies(enemies []gamelogic.Player) (gamelogic.Location, bool) {
+	var best gamelogic.Location
+	most := 0
+	for _, loc := range gamelogic.AllLocations() {
+		if n := len(enemyUnitsAt(enemies, loc)); n > most {
+			best, most = loc, n
+		}
+	}
+	return best, most > 0
+}
+
+func affordableRanks(rules gamelogic.Ruleset, funds int) []gamelogic.UnitRank {
+	ranks := []gamelogic.UnitRank{}
+	for _, name := range rules.RankNames() {
+		if rules.Ranks[name].Cost <= funds {
+			ranks = append(ranks, name)
+		}
+	}
+	return ranks
+}
+
+// pickRank returns the affordable rank with the highest score, ties go to the
+// first rank by name
+func pickRank(rules gamelogic.Ruleset, funds int, score func(gamelogic.RankRules) float64) (gamelogic.UnitRank, bool) {
+	var best gamelogic.UnitRank
+	found := false
+	for _, name := range affordableRanks(rules, funds) {
+		if !found || score(rules.Ranks[name]) > score(rules.Ranks[best]) {
+			best, found = name, true
+		}
+	}
+	return best, found
+}
+
+func cheapestRank(rules gamelogic.Ruleset, funds int) (gamelogic.UnitRank, bool) {
+	return pickRank(rules, funds, func(r gamelogic.RankRules) float64 { return -float64(r.Cost) })
+}
+
+func strongestRank(rules gamelogic.Ruleset, funds int) (gamelogic.UnitRank, bool) {
+	return pickRank(rules, funds, func(r gamelogic.RankRules) float64 { return float64(r.Power) })
+}
+
+func bestValueRank(rules gamelogic.Ruleset, funds int) (gamelogic.UnitRank, bool) {
+	return pickRank(rules, funds, func(r gamelogic.RankRules) float64 {
+		if r.Cost == 0 {
+			return float64(r.Power) * 1000
+		}
+		return float64(r.Power) / float64(r.Cost)
+	})
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/config"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	kindMove = "moves"
+	kindWar  = "wars"
+	kindLog  = "logs"
+)
+
+var messageKinds = []string{kindMove, kindWar, kindLog}
+
+// headers stamped on every load message so the consumer can measure the lag
+// and ignore the traffic of real players
+const (
+	headerRun    = "x-load-run"
+	headerKind   = "x-load-kind"
+	headerSentAt = "x-sent-at"
+)
+
+// mix is how often each kind of message is picked, e.g. moves=70,wars=10,logs=20
+type mix map[string]int
+
+func parseMix(s string) (mix, error) {
+	m := mix{}
+	for _, part := range strings.Split(s, ",") {
+		kind, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("error: expected <kind>=<weight>, got %q", part)
+		}
+		found := false
+		for _, k := range messageKinds {
+			found = found || k == kind
+		}
+		if !found {
+			return nil, fmt.Errorf("error: unknown message kind %q, use moves, wars or logs", kind)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("error: invalid weight %q for %s", weight, kind)
+		}
+		m[kind] = w
+	}
+	total := 0
+	for _, w := range m {
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("error: the mix needs at least one positive weight")
+	}
+	return m, nil
+}
+
+func (m mix) pick(rng *rand.Rand) string {
+	total := 0
+	for _, kind := range messageKinds {
+		total += m[kind]
+	}
+	n := rng.Intn(total)
+	for _, kind := range messageKinds {
+		if n < m[kind] {
+			return kind
+		}
+		n -= m[kind]
+	}
+	return messageKinds[0]
+}
+
+type loadConfig struct {
+	run     string
+	game    string
+	players []string
+	rate    float64
+	mix     mix
+}
+
+// sent is a publish waiting for its confirm
+type sent struct {
+	kind string
+	at   time.Time
+}
+
+// worker publishes for a share of the virtual players on its own connection,
+// in confirm mode so we know when the broker took each message
+type worker struct {
+	cfg     loadConfig
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	rng     *rand.Rand
+	stats   *counters
+	publish *latencies
+
+	mu      sync.Mutex
+	pending map[uint64]sent
+	done    chan struct{}
+}
+
+func newWorker(broker config.Config, cfg loadConfig, seed int64, stats *counters, publish *latencies) (*worker, error) {
+	conn, err := broker.Dial()
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	err = ch.Confirm(false)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	w := &worker{
+		cfg:     cfg,
+		conn:    conn,
+		ch:      ch,
+		rng:     rand.New(rand.NewSource(seed)),
+		stats:   stats,
+		publish: publish,
+		pending: map[uint64]sent{},
+		done:    make(chan struct{}),
+	}
+	go w.confirms(ch.NotifyPublish(make(chan amqp.Confirmation, 1024)))
+	return w, nil
+}
+
+func (w *worker) confirms(confirmations chan amqp.Confirmation) {
+	defer close(w.done)
+	for c := range confirmations {
+		w.mu.Lock()
+		s, ok := w.pending[c.DeliveryTag]
+		delete(w.pending, c.DeliveryTag)
+		w.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if !c.Ack {
+			w.stats.inc(w.stats.nacked, s.kind)
+			continue
+		}
+		w.stats.inc(w.stats.confirmed, s.kind)
+		w.publish.add(time.Since(s.at))
+	}
+}
+
+func (w *worker) unconfirmed() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
+
+// run publishes at rate messages per second for each of the worker's players
+// until stop is closed, catching up in bursts when it falls behind
+func (w *worker) run(stop chan struct{}) {
+	if len(w.cfg.players) == 0 || w.cfg.rate <= 0 {
+		return
+	}
+	interval := time.Duration(float64(time.Second) / (w.cfg.rate * float64(len(w.cfg.players))))
+	next := time.Now()
+	player := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Until(next)):
+		}
+		for !next.After(time.Now()) {
+			w.send(w.cfg.players[player])
+			player = (player + 1) % len(w.cfg.players)
+			next = next.Add(interval)
+		}
+	}
+}
+
+func (w *worker) send(player string) {
+	kind := w.cfg.mix.pick(w.rng)
+	exchange, key, msg, err := w.message(kind, player)
+	if err != nil {
+		w.stats.inc(w.stats.failed, kind)
+		return
+	}
+	w.stats.inc(w.stats.attempted, kind)
+
+	now := time.Now()
+	msg.Headers = amqp.Table{
+		headerRun:    w.cfg.run,
+		headerKind:   kind,
+		headerSentAt: now.UnixNano(),
+	}
+	tag := w.ch.GetNextPublishSeqNo()
+	w.mu.Lock()
+	w.pending[tag] = sent{kind: kind, at: now}
+	w.mu.Unlock()
+	err = w.ch.PublishWithContext(context.Background(), exchange, key, false, false, msg)
+	if err != nil {
+		w.mu.Lock()
+		delete(w.pending, tag)
+		w.mu.Unlock()
+		w.stats.inc(w.stats.failed, kind)
+	}
+}
+
+// message builds the same payloads real clients send, so the server and the
+// dashboard see ordinary traffic
+func (w *worker) message(kind, player string) (string, string, amqp.Publishing, error) {
+	locations := gamelogic.AllLocations()
+	location := locations[w.rng.Intn(len(locations))]
+	switch kind {
+	case kindMove:
+		body, err := json.Marshal(gamelogic.ArmyMove{
+			Game:       w.cfg.game,
+			Player:     gamelogic.Player{Username: player},
+			Units:      []gamelogic.Unit{{ID: w.rng.Intn(100) + 1, Rank: gamelogic.RankInfantry, Location: location}},
+			ToLocation: location,
+			ArrivesAt:  time.Now().Add(time.Minute),
+		})
+		return routing.ExchangePerilTopic, routing.GameKey(routing.ArmyMovesPrefix, w.cfg.game, player), amqp.Publishing{ContentType: "application/json", Body: body}, err
+	case kindWar:
+		defender := w.cfg.players[w.rng.Intn(len(w.cfg.players))]
+		body, err := json.Marshal(gamelogic.RecognitionOfWar{
+			Game:     w.cfg.game,
+			Attacker: gamelogic.Player{Username: player},
+			Defender: gamelogic.Player{Username: defender},
+			Location: location,
+			Seed:     w.rng.Int63(),
+		})
+		return routing.ExchangeWarTopic, routing.GameKey(routing.WarRecognitionsPrefix, w.cfg.game, player), amqp.Publishing{ContentType: "application/json", Body: body}, err
+	default:
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(routing.GameLog{
+			CurrentTime: time.Now(),
+			Message:     fmt.Sprintf("%s is generating load", player),
+			Username:    player,
+			Game:        w.cfg.game,
+		})
+		return routing.ExchangeGameLogs, routing.GameKey(routing.GameLogSlug, w.cfg.game, player), amqp.Publishing{ContentType: "application/gob", Body: buf.Bytes()}, err
+	}
+}
+
+// close waits for the confirms still on their way, up to the deadline
+func (w *worker) close(deadline time.Time) {
+	for w.unconfirmed() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	w.conn.Close()
+	<-w.done
+}
+
+// consume binds a transient queue to every key the load publishes on and
+// counts the messages of this run as they come back
+func consume(conn *amqp.Connection, cfg loadConfig, stats *counters, lag *latencies) error {
+	queueName := "perilload." + cfg.run
+	ch, queue, err := pubsub.DeclareAndBindNotDLQ(conn, routing.ExchangePerilTopic, queueName, routing.GameKey(routing.ArmyMovesPrefix, cfg.game, "*"), pubsub.TransientQueue)
+	if err != nil {
+		return err
+	}
+	err = ch.QueueBind(queue.Name, routing.GameKey(routing.WarRecognitionsPrefix, cfg.game, "*"), routing.ExchangeWarTopic, false, nil)
+	if err != nil {
+		return err
+	}
+	err = ch.QueueBind(queue.Name, routing.GameKey(routing.GameLogSlug, cfg.game, "*"), routing.ExchangeGameLogs, false, nil)
+	if err != nil {
+		return err
+	}
+	deliveries, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for d := range deliveries {
+			if run, _ := d.Headers[headerRun].(string); run != cfg.run {
+				continue
+			}
+			kind, _ := d.Headers[headerKind].(string)
+			stats.inc(stats.received, kind)
+			if sentAt, ok := d.Headers[headerSentAt].(int64); ok {
+				lag.add(time.Since(time.Unix(0, sentAt)))
+			}
+		}
+	}()
+	return nil
+}
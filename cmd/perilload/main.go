@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/config"
+)
+
+func main() {
+	players := flag.Int("players", 1000, "number of virtual players")
+	rate := flag.Float64("rate", 0.5, "messages per second sent by each player")
+	duration := flag.Duration("duration", 30*time.Second, "how long to generate load")
+	mixFlag := flag.String("mix", "moves=70,wars=10,logs=20", "relative weights of the messages sent")
+	game := flag.String("game", "load", "game the load is sent to")
+	connections := flag.Int("connections", 10, "connections the players are spread over")
+	drain := flag.Duration("drain", 10*time.Second, "how long to wait for confirms and deliveries after the load stops")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the generated messages")
+	loader := config.Flags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cfg.Apply()
+
+	m, err := parseMix(*mixFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *players < 1 || *connections < 1 {
+		fmt.Println("error: players and connections must be at least 1")
+		os.Exit(1)
+	}
+	*connections = min(*connections, *players)
+
+	run := strconv.FormatInt(time.Now().UnixNano(), 36)
+	stats := newCounters()
+	publish := &latencies{}
+	lag := &latencies{}
+
+	conn, err := cfg.Dial()
+	if err != nil {
+		fmt.Println("Failed to connect to RabbitMQ")
+		panic(err)
+	}
+	defer conn.Close()
+	names := make([]string, *players)
+	for i := range names {
+		names[i] = fmt.Sprintf("load-%d", i+1)
+	}
+	err = consume(conn, loadConfig{run: run, game: *game}, stats, lag)
+	if err != nil {
+		fmt.Println("Failed to start the consumer")
+		panic(err)
+	}
+
+	var workers []*worker
+	for i := 0; i < *connections; i++ {
+		lc := loadConfig{run: run, game: *game, rate: *rate, mix: m}
+		for p := i; p < len(names); p += *connections {
+			lc.players = append(lc.players, names[p])
+		}
+		w, err := newWorker(cfg, lc, *seed+int64(i), stats, publish)
+		if err != nil {
+			fmt.Printf("Failed to open connection %d: %v\n", i+1, err)
+			continue
+		}
+		workers = append(workers, w)
+	}
+	if len(workers) == 0 {
+		fmt.Println("error: no connection could be opened")
+		os.Exit(1)
+	}
+
+	fmt.Printf("Run %s: %v players at %v msg/s each over %v connection(s) for %v\n", run, *players, *rate, len(workers), *duration)
+	start := time.Now()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(stop)
+		}()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	timer := time.NewTimer(*duration)
+	progress := time.NewTicker(5 * time.Second)
+	defer progress.Stop()
+wait:
+	for {
+		select {
+		case <-timer.C:
+			break wait
+		case <-sig:
+			fmt.Println("Stopping the load early...")
+			break wait
+		case <-progress.C:
+			printProgress(stats, time.Since(start))
+		}
+	}
+	close(stop)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	fmt.Println("Waiting for the last confirms and deliveries...")
+	deadline := time.Now().Add(*drain)
+	for _, w := range workers {
+		w.close(deadline)
+	}
+	waitForDeliveries(stats, deadline)
+	printReport(stats, publish, lag, elapsed)
+}
+
+func printProgress(c *counters, elapsed time.Duration) {
+	var attempted, confirmed, received int
+	for _, kind := range messageKinds {
+		attempted += c.get(c.attempted, kind)
+		confirmed += c.get(c.confirmed, kind)
+		received += c.get(c.received, kind)
+	}
+	fmt.Printf("%v: %v published, %v confirmed, %v received\n", elapsed.Round(time.Second), attempted, confirmed, received)
+}
+
+// waitForDeliveries gives the consumer until the deadline to catch up with
+// everything the broker confirmed
+func waitForDeliveries(c *counters, deadline time.Time) {
+	for time.Now().Before(deadline) {
+		behind := false
+		for _, kind := range messageKinds {
+			behind = behind || c.get(c.received, kind) < c.get(c.confirmed, kind)
+		}
+		if !behind {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
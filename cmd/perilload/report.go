@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencies collects durations to report their percentiles at the end
+type latencies struct {
+	mu      sync.Mutex
+	samples []time.Duration
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples = append(l.samples, d)
+}
+
+func (l *latencies) sorted() []time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	samples := append([]time.Duration{}, l.samples...)
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p / 100)
+	return sorted[i]
+}
+
+// counters are updated by every worker while the test runs
+type counters struct {
+	mu        sync.Mutex
+	attempted map[string]int
+	confirmed map[string]int
+	nacked    map[string]int
+	failed    map[string]int
+	received  map[string]int
+}
+
+func newCounters() *counters {
+	return &counters{
+		attempted: map[string]int{},
+		confirmed: map[string]int{},
+		nacked:    map[string]int{},
+		failed:    map[string]int{},
+		received:  map[string]int{},
+	}
+}
+
+func (c *counters) inc(counter map[string]int, kind string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter[kind]++
+}
+
+func (c *counters) get(counter map[string]int, kind string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return counter[kind]
+}
+
+func printLatencies(name string, l *latencies) {
+	sorted := l.sorted()
+	if len(sorted) == 0 {
+		fmt.Printf("%-16s no samples\n", name)
+		return
+	}
+	fmt.Printf("%-16s p50 %-10v p90 %-10v p99 %-10v max %v\n", name,
+		percentile(sorted, 50).Round(time.Microsecond),
+		percentile(sorted, 90).Round(time.Microsecond),
+		percentile(sorted, 99).Round(time.Microsecond),
+		sorted[len(sorted)-1].Round(time.Microsecond))
+}
+
+// printReport sums up the run, drops are the confirmed messages our own
+// consumer never saw
+func printReport(c *counters, publish, lag *latencies, elapsed time.Duration) {
+	fmt.Println()
+	fmt.Printf("==== Load report (%v) ====\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("%-8s %10s %10s %8s %8s %10s %8s\n", "kind", "published", "confirmed", "nacked", "failed", "received", "dropped")
+	total := map[string]int{}
+	for _, kind := range messageKinds {
+		confirmed := c.get(c.confirmed, kind)
+		received := c.get(c.received, kind)
+		row := map[string]int{
+			"attempted": c.get(c.attempted, kind),
+			"confirmed": confirmed,
+			"nacked":    c.get(c.nacked, kind),
+			"failed":    c.get(c.failed, kind),
+			"received":  received,
+			"dropped":   max(confirmed-received, 0),
+		}
+		for k, v := range row {
+			total[k] += v
+		}
+		printRow(kind, row)
+	}
+	printRow("total", total)
+	fmt.Println()
+
+	seconds := elapsed.Seconds()
+	fmt.Printf("Throughput: %.1f msg/s published, %.1f msg/s received\n", float64(total["confirmed"])/seconds, float64(total["received"])/seconds)
+	if total["confirmed"] > 0 {
+		fmt.Printf("Drop rate: %.2f%%\n", 100*float64(total["dropped"])/float64(total["confirmed"]))
+	}
+	printLatencies("publish latency", publish)
+	printLatencies("consume lag", lag)
+}
+
+func printRow(kind string, row map[string]int) {
+	fmt.Printf("%-8s %10d %10d %8d %8d %10d %8d\n", kind, row["attempted"], row["confirmed"], row["nacked"], row["failed"], row["received"], row["dropped"])
+}
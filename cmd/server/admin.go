@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// adminResponse is the answer to every admin action
+type adminResponse struct {
+	Message string `json:",omitempty"`
+	Error   string `json:",omitempty"`
+}
+
+type adminPauseRequest struct {
+	Game string
+	// For is a duration like 5m and At a time like 18:00, both are optional
+	For string
+	At  string
+}
+
+type adminGameRequest struct {
+	Game string
+}
+
+type adminAnnounceRequest struct {
+	Game string
+	Text string
+}
+
+type adminKickRequest struct {
+	Player string
+}
+
+// adminHandler serves the admin API, it runs the same commands as the REPL
+func adminHandler(cmds *commands) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/games", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, cmds.games.list())
+	})
+	mux.HandleFunc("GET /api/players", func(w http.ResponseWriter, r *http.Request) {
+		players, err := cmds.players(r.URL.Query().Get("game"))
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, adminResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, players)
+	})
+	mux.HandleFunc("GET /api/logs", func(w http.ResponseWriter, r *http.Request) {
+		limit := 50
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				writeJSON(w, http.StatusBadRequest, adminResponse{Error: fmt.Sprintf("error: %s is not a valid limit", s)})
+				return
+			}
+			limit = n
+		}
+		writeJSON(w, http.StatusOK, cmds.logs.last(limit))
+	})
+	mux.HandleFunc("POST /api/pause", adminAction(func(req adminPauseRequest) (string, error) {
+		args := []string{}
+		if req.At != "" {
+			args = append(args, "at", req.At)
+		}
+		if req.For != "" {
+			args = append(args, "for", req.For)
+		}
+		if req.Game != "" {
+			args = append(args, req.Game)
+		}
+		return cmds.pause(args)
+	}))
+	mux.HandleFunc("POST /api/resume", adminAction(func(req adminGameRequest) (string, error) {
+		if req.Game == "" {
+			return cmds.resume(nil)
+		}
+		return cmds.resume([]string{req.Game})
+	}))
+	mux.HandleFunc("POST /api/announce", adminAction(func(req adminAnnounceRequest) (string, error) {
+		return cmds.announce(req.Game, req.Text)
+	}))
+	mux.HandleFunc("POST /api/kick", adminAction(func(req adminKickRequest) (string, error) {
+		return cmds.moderate([]string{"kick", req.Player})
+	}))
+	return mux
+}
+
+// adminAction decodes the request body and runs a command with it
+func adminAction[Req any](command func(Req) (string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if r.ContentLength != 0 {
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, adminResponse{Error: fmt.Sprintf("error: could not parse the request: %v", err)})
+				return
+			}
+		}
+		msg, err := command(req)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, adminResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, adminResponse{Message: msg})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		fmt.Println("Failed to write admin response")
+	}
+}
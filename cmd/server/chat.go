@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+)
+
+// chatHistoryLimit is how many messages are kept in memory for every game, the
+// file keeps all of them
+const chatHistoryLimit = 500
+
+const defaultHistoryLength = 20
+
+// chatStore appends every chat message to a JSON lines file
+type chatStore struct {
+	mu       sync.RWMutex
+	path     string
+	messages map[string][]gamelogic.ChatMessage
+}
+
+func loadChat(path string) (*chatStore, error) {
+	store := &chatStore{
+		path:     path,
+		messages: map[string][]gamelogic.ChatMessage{},
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open chat history: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg gamelogic.ChatMessage
+		err := json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse chat history: %v", err)
+		}
+		store.remember(msg)
+	}
+	return store, scanner.Err()
+}
+
+// remember must be called with the lock held
+func (s *chatStore) remember(msg gamelogic.ChatMessage) {
+	msgs := append(s.messages[msg.Game], msg)
+	if len(msgs) > chatHistoryLimit {
+		msgs = msgs[len(msgs)-chatHistoryLimit:]
+	}
+	s.messages[msg.Game] = msgs
+}
+
+func (s *chatStore) record(msg gamelogic.ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open chat history: %v", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("could not write chat history: %v", err)
+	}
+	s.remember(msg)
+	return nil
+}
+
+func (s *chatStore) history(req gamelogic.ChatHistoryRequest) []gamelogic.ChatMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLength
+	}
+	msgs := []gamelogic.ChatMessage{}
+	for _, msg := range s.messages[req.Game] {
+		if msg.Public() {
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(msgs) > limit {
+		msgs = msgs[len(msgs)-limit:]
+	}
+	return msgs
+}
+
+func handlerChat(chat *chatStore, bans *banStore) func(gamelogic.ChatMessage) pubsub.AckType {
+	return func(msg gamelogic.ChatMessage) pubsub.AckType {
+		// muted players that don't obey are still kept out of the history
+		if bans.isMuted(msg.From) {
+			return pubsub.NackDiscard
+		}
+		err := chat.record(msg)
+		if err != nil {
+			fmt.Println(err)
+			return pubsub.NackRequeue
+		}
+		return pubsub.Ack
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const announcer = "server"
+
+// commands runs the admin commands, both the REPL and the HTTP API go through
+// it. Every command returns what to tell the admin.
+type commands struct {
+	conn     *amqp.Connection
+	ch       *amqp.Channel
+	rules    gamelogic.Ruleset
+	games    *lobby
+	bans     *banStore
+	schedule *pauseSchedule
+	logs     *recentLogs
+}
+
+// playerInfo is what the admin sees of a player
+type playerInfo struct {
+	Username string
+	Game     string
+	Units    int
+	Regions  []gamelogic.Location
+	Muted    bool
+}
+
+func (c *commands) pause(args []string) (string, error) {
+	req, err := parsePause(args, time.Now())
+	if err != nil {
+		return "", err
+	}
+	ids, err := targetGames(c.games, req.game)
+	if err != nil {
+		return "", err
+	}
+	if !req.start.IsZero() {
+		c.schedule.add(scheduledPause{
+			Game:     req.game,
+			Start:    req.start,
+			Duration: req.duration,
+			Reason:   "scheduled pause",
+		})
+		return fmt.Sprintf("Pause scheduled at %s", req.start.Format("Mon 15:04")), nil
+	}
+	var resumeAt time.Time
+	if req.duration > 0 {
+		resumeAt = time.Now().Add(req.duration)
+	}
+	publishPlayingState(c.ch, c.games, ids, pauseUpdate("paused by the server", resumeAt))
+	return fmt.Sprintf("Paused %v", ids), nil
+}
+
+func (c *commands) resume(args []string) (string, error) {
+	game := ""
+	if len(args) > 0 {
+		game = args[0]
+	}
+	ids, err := targetGames(c.games, game)
+	if err != nil {
+		return "", err
+	}
+	publishPlayingState(c.ch, c.games, ids, resumeUpdate)
+	return fmt.Sprintf("Resumed %v", ids), nil
+}
+
+// maintenance schedules a daily pause or clears the schedule
+func (c *commands) maintenance(args []string) (string, error) {
+	if len(args) > 0 && args[0] == "clear" {
+		c.schedule.clear()
+		return "Cleared every scheduled pause", nil
+	}
+	if len(args) < 2 {
+		return "", errors.New("usage: maintenance <hh:mm> <duration> [game]")
+	}
+	start, err := parseClock(args[0], time.Now())
+	if err != nil {
+		return "", err
+	}
+	duration, err := time.ParseDuration(args[1])
+	if err != nil || duration <= 0 {
+		return "", fmt.Errorf("error: %s is not a valid duration", args[1])
+	}
+	game := ""
+	if len(args) > 2 {
+		if _, err := targetGames(c.games, args[2]); err != nil {
+			return "", err
+		}
+		game = args[2]
+	}
+	c.schedule.add(scheduledPause{
+		Game:     game,
+		Start:    start,
+		Duration: duration,
+		Reason:   "maintenance",
+		Daily:    true,
+	})
+	return fmt.Sprintf("Maintenance scheduled every day at %s for %v", args[0], duration), nil
+}
+
+func (c *commands) create(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", errors.New("usage: create <game> [turns]")
+	}
+	id := args[0]
+	turnBased := len(args) > 1 && args[1] == "turns"
+	if turnBased && c.rules.Turns.Duration.Duration == 0 {
+		return "", errors.New("error: the ruleset does not allow turn-based games")
+	}
+	if err := c.games.create(id, turnBased); err != nil {
+		return "", err
+	}
+	if !turnBased {
+		return fmt.Sprintf("Created game %s", id), nil
+	}
+	turnChannel, err := c.conn.Channel()
+	if err != nil {
+		c.games.close(id)
+		return "", errors.New("failed to open a channel")
+	}
+	go runTurns(turnChannel, c.games, id, c.rules.Turns.Duration.Duration)
+	return fmt.Sprintf("Created turn-based game %s with %v turns", id, c.rules.Turns.Duration), nil
+}
+
+func (c *commands) close(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", errors.New("usage: close <game>")
+	}
+	id := args[0]
+	if err := c.games.close(id); err != nil {
+		return "", err
+	}
+	err := pubsub.PublishJSON(c.ch, routing.ExchangePerilDirect, routing.GameKey(routing.GameClosedPrefix, id), routing.GameClosed{
+		Game:   id,
+		Reason: "the server closed the game",
+	})
+	if err != nil {
+		return "", errors.New("failed to publish the game closing")
+	}
+	return fmt.Sprintf("Closed game %s", id), nil
+}
+
+// moderate runs kick, ban, unban, mute and unmute, words starts with the
+// command name
+func (c *commands) moderate(words []string) (string, error) {
+	err := moderate(c.ch, c.games, c.bans, words)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Done: %s %s", words[0], words[1]), nil
+}
+
+// announce sends a message from the server to the global chat of a game, or
+// of every game when there is none
+func (c *commands) announce(game, text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("usage: announce <message>")
+	}
+	ids, err := targetGames(c.games, game)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		msg := gamelogic.ChatMessage{
+			Game:    id,
+			Channel: gamelogic.ChatGlobal,
+			From:    announcer,
+			Text:    text,
+			SentAt:  time.Now(),
+		}
+		err := pubsub.PublishJSON(c.ch, routing.ExchangePerilChat, routing.GameKey(routing.ChatPrefix, id, string(msg.Channel)), msg)
+		if err != nil {
+			return "", fmt.Errorf("failed to announce to %s", id)
+		}
+	}
+	return fmt.Sprintf("Announced to %v", ids), nil
+}
+
+// players lists the players of a game, or of every game when there is none
+func (c *commands) players(game string) ([]playerInfo, error) {
+	ids, err := targetGames(c.games, game)
+	if err != nil {
+		return nil, err
+	}
+	players := []playerInfo{}
+	for _, id := range ids {
+		info, ok := c.games.get(id)
+		if !ok {
+			continue
+		}
+		world, _ := c.games.world(id)
+		for _, username := range info.Players {
+			p := playerInfo{
+				Username: username,
+				Game:     id,
+				Regions:  []gamelogic.Location{},
+				Muted:    c.bans.isMuted(username),
+			}
+			if world != nil {
+				if snap, ok := world.GetPlayer(username); ok {
+					p.Units = len(snap.Units)
+				}
+				p.Regions = world.RegionsControlled(username)
+			}
+			players = append(players, p)
+		}
+	}
+	return players, nil
+}
+
+func printPlayers(players []playerInfo) {
+	if len(players) == 0 {
+		fmt.Println("Nobody is playing.")
+		return
+	}
+	for _, p := range players {
+		muted := ""
+		if p.Muted {
+			muted = " (muted)"
+		}
+		fmt.Printf("* %s in %s: %v units, holds %v%s\n", p.Username, p.Game, p.Units, p.Regions, muted)
+	}
+}
+
+// report prints the result of a command in the REPL
+func report(msg string, err error) {
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(msg)
+}
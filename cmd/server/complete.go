@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/lineedit"
+)
+
+// serverCommands are completed as the first word of a line
+var serverCommands = []string{"announce", "ban", "bans", "close", "create", "games", "help", "kick", "leaderboard", "maintenance", "mute", "pause", "players", "quit", "resume", "unban", "unmute"}
+
+// serverCompleter completes the commands, the players online for the
+// moderation commands and the games for the ones taking a game
+func serverCompleter(games *lobby, bans *banStore) lineedit.Completer {
+	return func(words []string) []string {
+		if len(words) == 0 {
+			return serverCommands
+		}
+		if len(words) > 1 {
+			return nil
+		}
+		switch words[0] {
+		case "kick", "ban", "mute", "unmute":
+			return onlinePlayers(games)
+		case "unban":
+			return bans.list()
+		case "resume", "close", "players":
+			return games.ids()
+		case "maintenance":
+			return []string{"clear"}
+		}
+		return nil
+	}
+}
+
+func onlinePlayers(games *lobby) []string {
+	online := map[string]bool{}
+	for _, game := range games.list() {
+		for _, username := range game.Players {
+			online[username] = true
+		}
+	}
+	names := []string{}
+	for name := range online {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+const (
+	dashboardRefresh = 2 * time.Second
+	// dashboardBuffer is how many events a slow spectator can fall behind
+	// before we start dropping theirs
+	dashboardBuffer = 64
+)
+
+// dashboardEvent is sent to spectators as a server-sent event named after
+// its Type
+type dashboardEvent struct {
+	Type string
+	Game string
+	At   time.Time
+	Data any
+}
+
+// worldView is a snapshot of a game for spectators
+type worldView struct {
+	Game    string
+	Paused  bool
+	Players []string
+	Regions []gamelogic.RegionView
+}
+
+// dashboardHub fans the game events out to every connected spectator
+type dashboardHub struct {
+	mu          sync.Mutex
+	subscribers map[chan dashboardEvent]struct{}
+	// id keeps the queues of servers sharing a broker apart
+	id string
+}
+
+func newDashboardHub() *dashboardHub {
+	return &dashboardHub{
+		subscribers: map[chan dashboardEvent]struct{}{},
+		id:          fmt.Sprintf("%x", rand.Int63()),
+	}
+}
+
+// queue names the hub's own queue for the messages under prefix
+func (h *dashboardHub) queue(prefix string) string {
+	return fmt.Sprintf("dashboard.%s.%s", h.id, prefix)
+}
+
+func (h *dashboardHub) subscribe() chan dashboardEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	events := make(chan dashboardEvent, dashboardBuffer)
+	h.subscribers[events] = struct{}{}
+	return events
+}
+
+func (h *dashboardHub) unsubscribe(events chan dashboardEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, events)
+}
+
+// publish never blocks, spectators that can't keep up miss events
+func (h *dashboardHub) publish(eventType, game string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ev := dashboardEvent{Type: eventType, Game: game, At: time.Now(), Data: data}
+	for events := range h.subscribers {
+		select {
+		case events <- ev:
+		default:
+		}
+	}
+}
+
+func handlerDashboardMove(hub *dashboardHub) func(gamelogic.ArmyMove) pubsub.AckType {
+	return func(move gamelogic.ArmyMove) pubsub.AckType {
+		hub.publish("move", move.Game, move)
+		return pubsub.Ack
+	}
+}
+
+func handlerDashboardWar(hub *dashboardHub) func(gamelogic.RecognitionOfWar) pubsub.AckType {
+	return func(rw gamelogic.RecognitionOfWar) pubsub.AckType {
+		hub.publish("war", rw.Game, rw)
+		return pubsub.Ack
+	}
+}
+
+func handlerDashboardWarResult(hub *dashboardHub) func(gamelogic.WarResult) pubsub.AckType {
+	return func(result gamelogic.WarResult) pubsub.AckType {
+		hub.publish("war_result", result.Game, result)
+		return pubsub.Ack
+	}
+}
+
+func handlerDashboardLog(hub *dashboardHub) func(routing.GameLog) pubsub.AckType {
+	return func(gl routing.GameLog) pubsub.AckType {
+		hub.publish("log", gl.Game, gl)
+		return pubsub.Ack
+	}
+}
+
+// dashboardHandler serves the spectator page, the game snapshots and the live
+// events
+func dashboardHandler(games *lobby, hub *dashboardHub) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardPage)
+	})
+	mux.HandleFunc("GET /games", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, games.list())
+	})
+	mux.HandleFunc("GET /world", func(w http.ResponseWriter, r *http.Request) {
+		view, ok := viewWorld(games, r.URL.Query().Get("game"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, adminResponse{Error: "error: no such game"})
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	})
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		streamEvents(w, r, games, hub)
+	})
+	return mux
+}
+
+func viewWorld(games *lobby, id string) (worldView, bool) {
+	info, ok := games.get(id)
+	if !ok {
+		return worldView{}, false
+	}
+	world, ok := games.world(id)
+	if !ok {
+		return worldView{}, false
+	}
+	ps, _ := games.state(id)
+	return worldView{
+		Game:    id,
+		Paused:  ps.IsPaused,
+		Players: info.Players,
+		Regions: world.Regions(),
+	}, true
+}
+
+// streamEvents sends the events of one game as server-sent events, with a
+// fresh snapshot of the world every few seconds
+func streamEvents(w http.ResponseWriter, r *http.Request, games *lobby, hub *dashboardHub) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	id := r.URL.Query().Get("game")
+	if _, ok := games.get(id); !ok {
+		http.Error(w, fmt.Sprintf("game %s does not exist", id), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	events := hub.subscribe()
+	defer hub.unsubscribe(events)
+	ticker := time.NewTicker(dashboardRefresh)
+	defer ticker.Stop()
+
+	sendWorld := func() bool {
+		view, ok := viewWorld(games, id)
+		if !ok {
+			return false
+		}
+		return writeEvent(w, flusher, dashboardEvent{Type: "world", Game: id, At: time.Now(), Data: view})
+	}
+	if !sendWorld() {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if !sendWorld() {
+				return
+			}
+		case ev := <-events:
+			if ev.Game != id {
+				continue
+			}
+			if !writeEvent(w, flusher, ev) {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, ev dashboardEvent) bool {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		fmt.Printf("Failed to encode a %s event\n", ev.Type)
+		return true
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+	if err != nil {
+		return false
+	}
+	flusher.Flush()
+	return true
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// watchIncome pays every player the income of the regions they control. Ticks
+// are numbered so clients can tell when they missed one.
+func watchIncome(ch *amqp.Channel, games *lobby, rules gamelogic.Ruleset) {
+	if rules.Economy.TickInterval.Duration == 0 {
+		return
+	}
+	ticks := map[string]int{}
+	ticker := time.NewTicker(rules.Economy.TickInterval.Duration)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, id := range games.ids() {
+			world, ok := games.world(id)
+			if !ok {
+				continue
+			}
+			ticks[id]++
+			tick := gamelogic.IncomeTick{
+				Game:   id,
+				Tick:   ticks[id],
+				Income: map[string]int{},
+			}
+			for _, p := range world.Players() {
+				tick.Income[p.Username] = world.Income(p.Username, rules)
+			}
+			err := pubsub.PublishJSON(ch, routing.ExchangePerilDirect, routing.GameKey(routing.IncomePrefix, id), tick)
+			if err != nil {
+				fmt.Printf("Failed to publish income to %s\n", id)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// With fog of war clients publish their moves to an exchange only the server
+// reads, it forwards every player only the part of a move they can see
+
+func handlerFogMove(ch *amqp.Channel, games *lobby) func(gamelogic.ArmyMove) pubsub.AckType {
+	return func(move gamelogic.ArmyMove) pubsub.AckType {
+		world, ok := games.world(move.Game)
+		if !ok {
+			return pubsub.NackDiscard
+		}
+		for _, player := range otherPlayers(games, move.Game, move.Player.Username) {
+			filtered, ok := gamelogic.FilterMove(move, world.VisibleLocations(player))
+			if !ok {
+				continue
+			}
+			err := pubsub.PublishJSON(ch, routing.ExchangePerilTopic, routing.GameKey(routing.VisibleMovesPrefix, move.Game, player), filtered)
+			if err != nil {
+				fmt.Printf("Failed to forward a move to %s\n", player)
+			}
+		}
+		return pubsub.Ack
+	}
+}
+
+func handlerFogArrival(ch *amqp.Channel, games *lobby) func(gamelogic.ArmyArrival) pubsub.AckType {
+	return func(arrival gamelogic.ArmyArrival) pubsub.AckType {
+		world, ok := games.world(arrival.Game)
+		if !ok {
+			return pubsub.NackDiscard
+		}
+		for _, player := range otherPlayers(games, arrival.Game, arrival.Player.Username) {
+			filtered, ok := gamelogic.FilterArrival(arrival, world.VisibleLocations(player))
+			if !ok {
+				continue
+			}
+			err := pubsub.PublishJSON(ch, routing.ExchangePerilTopic, routing.GameKey(routing.VisibleArrivalsPrefix, arrival.Game, player), filtered)
+			if err != nil {
+				fmt.Printf("Failed to forward an arrival to %s\n", player)
+			}
+		}
+		return pubsub.Ack
+	}
+}
+
+func otherPlayers(games *lobby, id, username string) []string {
+	game, ok := games.get(id)
+	if !ok {
+		return nil
+	}
+	others := []string{}
+	for _, p := range game.Players {
+		if p != username {
+			others = append(others, p)
+		}
+	}
+	return others
+}
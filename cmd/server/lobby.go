@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+const defaultGame = "main"
+
+// game IDs end up in routing keys, so they can't contain dots or wildcards
+var gameIDPattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+type lobby struct {
+	mu     sync.RWMutex
+	games  map[string]*routing.GameInfo
+	worlds map[string]*gamelogic.World
+	states map[string]routing.PlayingState
+}
+
+func newLobby() *lobby {
+	l := &lobby{
+		games:  map[string]*routing.GameInfo{},
+		worlds: map[string]*gamelogic.World{},
+		states: map[string]routing.PlayingState{},
+	}
+	l.create(defaultGame, false)
+	return l
+}
+
+func (l *lobby) create(id string, turnBased bool) error {
+	if !gameIDPattern.MatchString(id) {
+		return fmt.Errorf("error: %s is not a valid game ID, use lowercase letters, digits, - and _", id)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.games[id]; ok {
+		return fmt.Errorf("error: game %s already exists", id)
+	}
+	l.games[id] = &routing.GameInfo{
+		ID:        id,
+		Players:   []string{},
+		CreatedAt: time.Now(),
+		TurnBased: turnBased,
+	}
+	l.worlds[id] = gamelogic.NewWorld()
+	l.states[id] = routing.PlayingState{}
+	return nil
+}
+
+func (l *lobby) close(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.games[id]; !ok {
+		return fmt.Errorf("error: game %s does not exist", id)
+	}
+	delete(l.games, id)
+	delete(l.worlds, id)
+	delete(l.states, id)
+	return nil
+}
+
+func (l *lobby) join(id, username string) (routing.GameInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	g, ok := l.games[id]
+	if !ok {
+		return routing.GameInfo{}, fmt.Errorf("error: game %s does not exist", id)
+	}
+	for _, p := range g.Players {
+		if p == username {
+			return copyGame(g), nil
+		}
+	}
+	g.Players = append(g.Players, username)
+	l.worlds[id].Start(time.Now())
+	return copyGame(g), nil
+}
+
+// leave removes a player from every game they joined
+func (l *lobby) leave(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, g := range l.games {
+		players := []string{}
+		for _, p := range g.Players {
+			if p != username {
+				players = append(players, p)
+			}
+		}
+		g.Players = players
+	}
+}
+
+func (l *lobby) get(id string) (routing.GameInfo, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	g, ok := l.games[id]
+	if !ok {
+		return routing.GameInfo{}, false
+	}
+	return copyGame(g), true
+}
+
+func (l *lobby) world(id string) (*gamelogic.World, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	w, ok := l.worlds[id]
+	return w, ok
+}
+
+func (l *lobby) state(id string) (routing.PlayingState, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ps, ok := l.states[id]
+	return ps, ok
+}
+
+// updateState changes the playing state of a game and returns the new one
+func (l *lobby) updateState(id string, update func(*routing.PlayingState)) (routing.PlayingState, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ps, ok := l.states[id]
+	if !ok {
+		return routing.PlayingState{}, false
+	}
+	update(&ps)
+	ps.Version++
+	l.states[id] = ps
+	return ps, true
+}
+
+func (l *lobby) list() []routing.GameInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	games := []routing.GameInfo{}
+	for _, g := range l.games {
+		games = append(games, copyGame(g))
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].ID < games[j].ID })
+	return games
+}
+
+func (l *lobby) ids() []string {
+	ids := []string{}
+	for _, g := range l.list() {
+		ids = append(ids, g.ID)
+	}
+	return ids
+}
+
+func copyGame(g *routing.GameInfo) routing.GameInfo {
+	players := make([]string, len(g.Players))
+	copy(players, g.Players)
+	return routing.GameInfo{
+		ID:        g.ID,
+		Players:   players,
+		CreatedAt: g.CreatedAt,
+		TurnBased: g.TurnBased,
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+const recentLogsSize = 200
+
+// recentLogs keeps the last game logs written to disk so admins can look at
+// them without reading the file
+type recentLogs struct {
+	mu   sync.RWMutex
+	logs []routing.GameLog
+}
+
+func newRecentLogs() *recentLogs {
+	return &recentLogs{}
+}
+
+func (r *recentLogs) add(gl routing.GameLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, gl)
+	if len(r.logs) > recentLogsSize {
+		r.logs = r.logs[len(r.logs)-recentLogsSize:]
+	}
+}
+
+// last returns up to limit logs, oldest first
+func (r *recentLogs) last(limit int) []routing.GameLog {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if limit <= 0 || limit > len(r.logs) {
+		limit = len(r.logs)
+	}
+	return append([]routing.GameLog{}, r.logs[len(r.logs)-limit:]...)
+}
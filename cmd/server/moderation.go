@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// banStore keeps the banned and muted players in a JSON file. A zero time is
+// a permanent ban.
+type banStore struct {
+	mu    sync.RWMutex
+	path  string
+	bans  map[string]time.Time
+	mutes map[string]bool
+}
+
+func loadBans(path string) (*banStore, error) {
+	store := &banStore{
+		path:  path,
+		bans:  map[string]time.Time{},
+		mutes: map[string]bool{},
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read bans: %v", err)
+	}
+	var file banFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if dec.Decode(&file) != nil {
+		// files saved before mutes were kept only have the bans
+		file = banFile{}
+		err = json.Unmarshal(data, &file.Bans)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse bans: %v", err)
+		}
+	}
+	for name, until := range file.Bans {
+		store.bans[name] = until
+	}
+	for _, name := range file.Mutes {
+		store.mutes[name] = true
+	}
+	return store, nil
+}
+
+type banFile struct {
+	Bans  map[string]time.Time
+	Mutes []string
+}
+
+// save must be called with the lock held
+func (s *banStore) save() error {
+	file := banFile{Bans: s.bans, Mutes: []string{}}
+	for name := range s.mutes {
+		file.Mutes = append(file.Mutes, name)
+	}
+	sort.Strings(file.Mutes)
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write bans: %v", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *banStore) ban(username string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bans[username] = until
+	return s.save()
+}
+
+func (s *banStore) unban(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.bans[username]; !ok {
+		return fmt.Errorf("error: %s is not banned", username)
+	}
+	delete(s.bans, username)
+	return s.save()
+}
+
+// banned reports whether the player is banned, and until when
+func (s *banStore) banned(username string, now time.Time) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	until, ok := s.bans[username]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !until.IsZero() && now.After(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+func (s *banStore) setMuted(username string, muted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if muted {
+		s.mutes[username] = true
+	} else {
+		delete(s.mutes, username)
+	}
+	return s.save()
+}
+
+func (s *banStore) isMuted(username string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mutes[username]
+}
+
+func (s *banStore) list() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := []string{}
+	for name := range s.bans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func banMessage(until time.Time) string {
+	if until.IsZero() {
+		return "you are banned from this server"
+	}
+	return fmt.Sprintf("you are banned from this server until %s", until.Format(time.RFC1123))
+}
+
+func publishControl(ch *amqp.Channel, msg routing.ControlMessage) error {
+	return pubsub.PublishJSON(ch, routing.ExchangePerilDirect, fmt.Sprintf("%s.%s", routing.ControlPrefix, msg.Target), msg)
+}
+
+// moderate runs the kick, ban, unban, mute and unmute commands
+func moderate(ch *amqp.Channel, games *lobby, bans *banStore, words []string) error {
+	if len(words) < 2 || words[1] == "" {
+		return fmt.Errorf("usage: %s <player>", words[0])
+	}
+	target := words[1]
+	msg := routing.ControlMessage{
+		Action: routing.ControlAction(words[0]),
+		Target: target,
+	}
+
+	switch msg.Action {
+	case routing.ControlKick:
+		msg.Reason = "you were kicked by the server"
+		games.leave(target)
+	case routing.ControlBan:
+		var until time.Time
+		if len(words) > 2 {
+			d, err := time.ParseDuration(words[2])
+			if err != nil || d <= 0 {
+				return fmt.Errorf("error: %s is not a valid duration", words[2])
+			}
+			until = time.Now().Add(d)
+		}
+		err := bans.ban(target, until)
+		if err != nil {
+			return err
+		}
+		msg.Until = until
+		msg.Reason = banMessage(until)
+		games.leave(target)
+	case routing.ControlUnban:
+		return bans.unban(target)
+	case routing.ControlMute:
+		err := bans.setMuted(target, true)
+		if err != nil {
+			return err
+		}
+		msg.Reason = "you were muted by the server"
+	case routing.ControlUnmute:
+		err := bans.setMuted(target, false)
+		if err != nil {
+			return err
+		}
+		msg.Reason = "you can talk again"
+	default:
+		return fmt.Errorf("error: %s is not a moderation command", words[0])
+	}
+	return publishControl(ch, msg)
+}
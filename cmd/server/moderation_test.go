@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBansSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	bans, err := loadBans(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bans.ban("mallory", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bans.setMuted("eve", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := bans.setMuted("trent", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := bans.setMuted("trent", false); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadBans(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.list(); !reflect.DeepEqual(got, []string{"mallory"}) {
+		t.Errorf("got bans %v, want [mallory]", got)
+	}
+	if !loaded.isMuted("eve") || loaded.isMuted("trent") {
+		t.Errorf("got eve muted %v and trent muted %v, want only eve", loaded.isMuted("eve"), loaded.isMuted("trent"))
+	}
+}
+
+func TestLoadOldBans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	err := os.WriteFile(path, []byte(`{"mallory": "0001-01-01T00:00:00Z"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bans, err := loadBans(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bans.banned("mallory", time.Now()); !ok {
+		t.Error("mallory is no longer banned")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const quarantineQueue = "game_logs_quarantine"
+
+// alertWindow is the period over which quarantined messages are counted
+// before alerting the admin
+const alertWindow = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type offender struct {
+	quarantined int
+	since       time.Time
+	alerted     bool
+}
+
+// rateLimiter gives every player a token bucket refilled at rate tokens per
+// second, up to burst. Players over their budget are offenders.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	threshold int
+	buckets   map[string]*bucket
+	offenders map[string]*offender
+}
+
+func newRateLimiter(rate float64, burst, threshold int) *rateLimiter {
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		threshold: threshold,
+		buckets:   map[string]*bucket{},
+		offenders: map[string]*offender{},
+	}
+}
+
+func (r *rateLimiter) allow(username string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buckets[username]
+	if !ok {
+		b = &bucket{tokens: r.burst, last: now}
+		r.buckets[username] = b
+	}
+	// logs can arrive out of order, an older one doesn't refill anything
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * r.rate
+		if b.tokens > r.burst {
+			b.tokens = r.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// offend records a quarantined message and reports whether the player just
+// went over the alert threshold
+func (r *rateLimiter) offend(username string, now time.Time) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.offenders[username]
+	if !ok || now.Sub(o.since) > alertWindow {
+		o = &offender{since: now}
+		r.offenders[username] = o
+	}
+	o.quarantined++
+	if o.quarantined >= r.threshold && !o.alerted {
+		o.alerted = true
+		return o.quarantined, true
+	}
+	return o.quarantined, false
+}
+
+// handlerLogs writes the game logs with write. Writing is slow, so players over
+// their rate limit have their logs quarantined instead of holding up everyone
+// else's.
+func handlerLogs(quarantine, write func(routing.GameLog) error, limiter *rateLimiter, logs *recentLogs, bans *banStore) func(routing.GameLog) pubsub.AckType {
+	return func(data routing.GameLog) pubsub.AckType {
+		// like chat, muted players that don't obey are kept out of the logs
+		if bans.isMuted(data.Username) {
+			return pubsub.NackDiscard
+		}
+		now := time.Now()
+		if !limiter.allow(data.Username, sentAt(data, now)) {
+			err := quarantine(data)
+			if err != nil {
+				fmt.Println("Failed to quarantine game log")
+				return pubsub.NackRequeue
+			}
+			count, alert := limiter.offend(data.Username, now)
+			if alert {
+				log.Printf("[ALERT] %s sent %v game logs over their rate limit in the last %v, their logs are being quarantined", data.Username, count, alertWindow)
+				fmt.Print("> ")
+			}
+			return pubsub.Ack
+		}
+
+		defer fmt.Println("> ")
+
+		err := write(data)
+		if err != nil {
+			fmt.Println(err)
+		}
+		logs.add(data)
+
+		return pubsub.Ack
+	}
+}
+
+// sentAt is when the player sent the log. The limit can't use the time we
+// read it, logs are read one slow write at a time so the bucket would refill
+// as fast as a flood is drained. Times from the future are not trusted.
+func sentAt(data routing.GameLog, now time.Time) time.Time {
+	if data.CurrentTime.IsZero() || data.CurrentTime.After(now) {
+		return now
+	}
+	return data.CurrentTime
+}
+
+// quarantineTo moves the logs of players over their limit to the quarantine
+// queue
+func quarantineTo(ch *amqp.Channel) func(routing.GameLog) error {
+	return func(data routing.GameLog) error {
+		return pubsub.PublishGob(ch, routing.ExchangeGameLogs, fmt.Sprintf("quarantine.%s", data.Username), data)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	type call struct {
+		username string
+		after    time.Duration
+		want     bool
+	}
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		calls []call
+	}{
+		{
+			name:  "burst then empty",
+			rate:  1,
+			burst: 2,
+			calls: []call{{"alice", 0, true}, {"alice", 0, true}, {"alice", 0, false}},
+		},
+		{
+			name:  "refills over time",
+			rate:  2,
+			burst: 1,
+			calls: []call{{"alice", 0, true}, {"alice", 0, false}, {"alice", 500 * time.Millisecond, true}},
+		},
+		{
+			name:  "partial refill is not enough",
+			rate:  1,
+			burst: 1,
+			calls: []call{{"alice", 0, true}, {"alice", 500 * time.Millisecond, false}, {"alice", time.Second, true}},
+		},
+		{
+			name:  "refill stops at the burst",
+			rate:  10,
+			burst: 2,
+			calls: []call{{"alice", 0, true}, {"alice", time.Hour, true}, {"alice", time.Hour, true}, {"alice", time.Hour, false}},
+		},
+		{
+			name:  "players have their own bucket",
+			rate:  1,
+			burst: 1,
+			calls: []call{{"alice", 0, true}, {"alice", 0, false}, {"bob", 0, true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRateLimiter(tt.rate, tt.burst, 1)
+			for i, c := range tt.calls {
+				if got := r.allow(c.username, start.Add(c.after)); got != c.want {
+					t.Fatalf("call %v for %s after %v: got %v, want %v", i, c.username, c.after, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerLogsBurst(t *testing.T) {
+	sent := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name        string
+		logs        int
+		gap         time.Duration
+		written     int
+		quarantined int
+	}{
+		{"sent at once", 12, 0, 5, 7},
+		{"sent within the rate", 12, time.Second, 12, 0},
+		{"sent twice as fast", 12, 500 * time.Millisecond, 10, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written, quarantined := 0, 0
+			handler := handlerLogs(
+				func(routing.GameLog) error { quarantined++; return nil },
+				// the real write takes a second, reading the logs slowly must
+				// not refill the bucket
+				func(routing.GameLog) error { written++; return nil },
+				newRateLimiter(1, 5, 100),
+				newRecentLogs(),
+				&banStore{mutes: map[string]bool{}},
+			)
+			for i := 0; i < tt.logs; i++ {
+				ack := handler(routing.GameLog{
+					Username:    "spammer",
+					Message:     "spam",
+					CurrentTime: sent.Add(time.Duration(i) * tt.gap),
+				})
+				if ack != pubsub.Ack {
+					t.Fatalf("log %v was not acked", i)
+				}
+			}
+			if written != tt.written || quarantined != tt.quarantined {
+				t.Errorf("wrote %v and quarantined %v logs, want %v and %v", written, quarantined, tt.written, tt.quarantined)
+			}
+		})
+	}
+}
+
+func TestHandlerLogsMuted(t *testing.T) {
+	written := 0
+	bans := &banStore{mutes: map[string]bool{"mallory": true}}
+	handler := handlerLogs(
+		func(routing.GameLog) error { return nil },
+		func(routing.GameLog) error { written++; return nil },
+		newRateLimiter(1, 5, 100),
+		newRecentLogs(),
+		bans,
+	)
+	if ack := handler(routing.GameLog{Username: "mallory", Message: "spam"}); ack != pubsub.NackDiscard {
+		t.Errorf("muted log got %v, want it discarded", ack)
+	}
+	if ack := handler(routing.GameLog{Username: "alice", Message: "hi"}); ack != pubsub.Ack {
+		t.Errorf("log got %v, want it acked", ack)
+	}
+	if written != 1 {
+		t.Errorf("wrote %v logs, want 1", written)
+	}
+}
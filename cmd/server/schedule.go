@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const scheduleCheckInterval = time.Second
+
+// scheduledPause pauses a game, or every game when Game is empty, at Start. A
+// zero Duration pauses it until someone resumes it by hand.
+type scheduledPause struct {
+	Game     string
+	Start    time.Time
+	Duration time.Duration
+	Reason   string
+	// Daily pauses are maintenance windows, they come back every day
+	Daily bool
+}
+
+type pauseSchedule struct {
+	mu     sync.Mutex
+	pauses []scheduledPause
+}
+
+func newPauseSchedule() *pauseSchedule {
+	return &pauseSchedule{}
+}
+
+func (s *pauseSchedule) add(p scheduledPause) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pauses = append(s.pauses, p)
+}
+
+func (s *pauseSchedule) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pauses = nil
+}
+
+func (s *pauseSchedule) list() []scheduledPause {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]scheduledPause{}, s.pauses...)
+}
+
+// due returns the pauses that should start now, daily ones are moved to the
+// next day and the others are dropped
+func (s *pauseSchedule) due(now time.Time) []scheduledPause {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []scheduledPause{}
+	pending := []scheduledPause{}
+	for _, p := range s.pauses {
+		if now.Before(p.Start) {
+			pending = append(pending, p)
+			continue
+		}
+		due = append(due, p)
+		if p.Daily {
+			for !now.Before(p.Start) {
+				p.Start = p.Start.Add(24 * time.Hour)
+			}
+			pending = append(pending, p)
+		}
+	}
+	s.pauses = pending
+	return due
+}
+
+// runSchedule starts the scheduled pauses and resumes the games whose pause
+// has run out
+func runSchedule(ch *amqp.Channel, games *lobby, schedule *pauseSchedule) {
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		for _, p := range schedule.due(now) {
+			ids := games.ids()
+			if p.Game != "" {
+				if _, ok := games.get(p.Game); !ok {
+					continue
+				}
+				ids = []string{p.Game}
+			}
+			var resumeAt time.Time
+			if p.Duration > 0 {
+				resumeAt = now.Add(p.Duration)
+			}
+			fmt.Printf("Starting %s in %v\n", p.Reason, ids)
+			publishPlayingState(ch, games, ids, pauseUpdate(p.Reason, resumeAt))
+		}
+
+		for _, id := range games.ids() {
+			ps, ok := games.state(id)
+			if !ok || !ps.IsPaused || ps.ResumeAt.IsZero() || now.Before(ps.ResumeAt) {
+				continue
+			}
+			fmt.Printf("Resuming %s after %s\n", id, ps.Reason)
+			publishPlayingState(ch, games, []string{id}, resumeUpdate)
+		}
+	}
+}
+
+func pauseUpdate(reason string, resumeAt time.Time) func(*routing.PlayingState) {
+	return func(ps *routing.PlayingState) {
+		ps.IsPaused = true
+		ps.Reason = reason
+		ps.ResumeAt = resumeAt
+	}
+}
+
+func resumeUpdate(ps *routing.PlayingState) {
+	ps.IsPaused = false
+	ps.Reason = ""
+	ps.ResumeAt = time.Time{}
+}
+
+// pauseRequest is a parsed pause command: pause [at <hh:mm>] [for <duration>] [game]
+type pauseRequest struct {
+	game     string
+	start    time.Time
+	duration time.Duration
+}
+
+func parsePause(args []string, now time.Time) (pauseRequest, error) {
+	req := pauseRequest{}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "for", "at":
+			if i+1 >= len(args) {
+				return pauseRequest{}, fmt.Errorf("error: %s needs a value", args[i])
+			}
+			var err error
+			if args[i] == "for" {
+				req.duration, err = time.ParseDuration(args[i+1])
+				if err == nil && req.duration <= 0 {
+					err = errors.New("the duration must be positive")
+				}
+			} else {
+				req.start, err = parseClock(args[i+1], now)
+			}
+			if err != nil {
+				return pauseRequest{}, fmt.Errorf("error: %s is not valid: %v", args[i+1], err)
+			}
+			i++
+		default:
+			if req.game != "" {
+				return pauseRequest{}, errors.New("usage: pause [at <hh:mm>] [for <duration>] [game]")
+			}
+			req.game = args[i]
+		}
+	}
+	return req, nil
+}
+
+// parseClock returns the next time the clock shows hh:mm, today or tomorrow
+func parseClock(clock string, now time.Time) (time.Time, error) {
+	t, err := time.ParseInLocation("15:04", clock, now.Location())
+	if err != nil {
+		return time.Time{}, errors.New("expected a time like 18:00")
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !start.After(now) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start, nil
+}
+
+// targetGames returns the game given as argument, or every game when there is
+// none
+func targetGames(games *lobby, id string) ([]string, error) {
+	if id == "" {
+		return games.ids(), nil
+	}
+	if _, ok := games.get(id); !ok {
+		return nil, fmt.Errorf("error: game %s does not exist", id)
+	}
+	return []string{id}, nil
+}
+
+func printSchedule(pauses []scheduledPause) {
+	if len(pauses) == 0 {
+		fmt.Println("No pauses are scheduled.")
+		return
+	}
+	for _, p := range pauses {
+		game := p.Game
+		if game == "" {
+			game = "every game"
+		}
+		length := "until resumed"
+		if p.Duration > 0 {
+			length = fmt.Sprintf("for %v", p.Duration)
+		}
+		repeat := ""
+		if p.Daily {
+			repeat = ", every day"
+		}
+		fmt.Printf("* %s: %s at %s %s%s\n", p.Reason, game, p.Start.Format("Mon 15:04"), length, repeat)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+)
+
+// statsStore keeps the stats of every player in a JSON file, it's rewritten
+// after every change
+type statsStore struct {
+	mu      sync.RWMutex
+	path    string
+	players map[string]*gamelogic.PlayerStats
+}
+
+func loadStats(path string) (*statsStore, error) {
+	store := &statsStore{
+		path:    path,
+		players: map[string]*gamelogic.PlayerStats{},
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read stats: %v", err)
+	}
+	err = json.Unmarshal(data, &store.players)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse stats: %v", err)
+	}
+	return store, nil
+}
+
+// save must be called with the lock held
+func (s *statsStore) save() error {
+	data, err := json.MarshalIndent(s.players, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so a crash can't leave half a file
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write stats: %v", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// player must be called with the lock held
+func (s *statsStore) player(username string) *gamelogic.PlayerStats {
+	p, ok := s.players[username]
+	if !ok {
+		p = &gamelogic.PlayerStats{Username: username}
+		s.players[username] = p
+	}
+	return p
+}
+
+func (s *statsStore) recordWar(result gamelogic.WarResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, username := range result.Participants() {
+		s.player(username).RecordWar(result)
+	}
+	return s.save()
+}
+
+func (s *statsStore) recordRegions(username string, regions int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.player(username)
+	if regions <= p.RegionsHeld {
+		return nil
+	}
+	p.RegionsHeld = regions
+	return s.save()
+}
+
+func (s *statsStore) leaderboard() []gamelogic.PlayerStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := []gamelogic.PlayerStats{}
+	for _, p := range s.players {
+		stats = append(stats, *p)
+	}
+	gamelogic.SortLeaderboard(stats)
+	return stats
+}
+
+func (s *statsStore) lookup(req gamelogic.StatsRequest) gamelogic.StatsResponse {
+	for i, p := range s.leaderboard() {
+		if p.Username == req.Username {
+			return gamelogic.StatsResponse{Stats: p, Rank: i + 1, Found: true}
+		}
+	}
+	return gamelogic.StatsResponse{Stats: gamelogic.PlayerStats{Username: req.Username}}
+}
+
+func handlerWarResult(stats *statsStore) func(gamelogic.WarResult) pubsub.AckType {
+	return func(result gamelogic.WarResult) pubsub.AckType {
+		err := stats.recordWar(result)
+		if err != nil {
+			fmt.Println(err)
+			return pubsub.NackRequeue
+		}
+		return pubsub.Ack
+	}
+}
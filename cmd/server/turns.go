@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const turnCheckInterval = time.Second
+
+// runTurns drives a turn-based game until it's closed. The clock stops while
+// the game is paused, the deadline is pushed back instead.
+func runTurns(ch *amqp.Channel, games *lobby, id string, duration time.Duration) {
+	startTurn(ch, games, id, 1, duration)
+	ticker := time.NewTicker(turnCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		ps, ok := games.state(id)
+		if !ok {
+			return
+		}
+		if ps.IsPaused {
+			games.updateState(id, func(ps *routing.PlayingState) {
+				ps.Deadline = ps.Deadline.Add(turnCheckInterval)
+			})
+			continue
+		}
+		if now.Before(ps.Deadline) {
+			continue
+		}
+		err := pubsub.PublishJSON(ch, routing.ExchangePerilDirect, routing.GameKey(routing.TurnEndPrefix, id), routing.TurnEnd{
+			Game: id,
+			Turn: ps.Turn,
+		})
+		if err != nil {
+			fmt.Printf("Failed to publish the end of turn %v to %s\n", ps.Turn, id)
+		}
+		startTurn(ch, games, id, ps.Turn+1, duration)
+	}
+}
+
+func startTurn(ch *amqp.Channel, games *lobby, id string, turn int, duration time.Duration) {
+	ps, ok := games.updateState(id, func(ps *routing.PlayingState) {
+		ps.Turn = turn
+		ps.Deadline = time.Now().Add(duration)
+	})
+	if !ok {
+		return
+	}
+	err := pubsub.PublishJSON(ch, routing.ExchangePerilDirect, routing.GameKey(routing.TurnStartPrefix, id), routing.TurnStart{
+		Game:     id,
+		Turn:     ps.Turn,
+		Deadline: ps.Deadline,
+	})
+	if err != nil {
+		fmt.Printf("Failed to publish the start of turn %v to %s\n", turn, id)
+	}
+	err = pubsub.PublishJSON(ch, routing.ExchangePerilDirect, routing.GameKey(routing.PauseKey, id), ps)
+	if err != nil {
+		fmt.Printf("Failed to publish the playing state to %s\n", id)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const victoryCheckInterval = 5 * time.Second
+
+func handlerPlayerStatus(games *lobby, stats *statsStore) func(gamelogic.PlayerStatus) pubsub.AckType {
+	return func(ps gamelogic.PlayerStatus) pubsub.AckType {
+		world, ok := games.world(ps.Game)
+		if !ok {
+			return pubsub.NackDiscard
+		}
+		world.UpdatePlayer(ps.Player)
+		err := stats.recordRegions(ps.Player.Username, len(world.RegionsControlled(ps.Player.Username)))
+		if err != nil {
+			fmt.Println(err)
+		}
+		return pubsub.Ack
+	}
+}
+
+// watchVictory ends every game that meets one of the victory conditions of
+// the ruleset
+func watchVictory(ch *amqp.Channel, games *lobby, rules gamelogic.Ruleset) {
+	ticker := time.NewTicker(victoryCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		for _, id := range games.ids() {
+			world, ok := games.world(id)
+			if !ok {
+				continue
+			}
+			over, ok := world.CheckVictory(rules, now)
+			if !ok {
+				continue
+			}
+			over.Game = id
+			endGame(ch, games, over)
+		}
+	}
+}
+
+func endGame(ch *amqp.Channel, games *lobby, over gamelogic.GameOver) {
+	fmt.Println()
+	fmt.Printf("Game %s is over, %s\n", over.Game, over.Reason)
+	fmt.Println(gamelogic.FormatScoreboard(over.Scoreboard))
+	defer fmt.Print("> ")
+
+	err := pubsub.PublishJSON(ch, routing.ExchangePerilDirect, routing.GameKey(routing.GameOverPrefix, over.Game), over)
+	if err != nil {
+		fmt.Println("Failed to publish the game over")
+	}
+	err = games.close(over.Game)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// there is always a default game to join
+	if over.Game == defaultGame {
+		err = games.create(defaultGame, false)
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Printf("Reopened game %s\n", defaultGame)
+		}
+	}
+
+	err = gamelogic.WriteLog(routing.GameLog{
+		CurrentTime: time.Now(),
+		Username:    "server",
+		Game:        over.Game,
+		Message:     fmt.Sprintf("game over, %s. Final scoreboard: %s", over.Reason, gamelogic.FormatScoreboard(over.Scoreboard)),
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+}
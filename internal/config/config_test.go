@@ -0,0 +1,108 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// clearEnv unsets every PERIL_* variable for the test, they are restored
+// afterwards
+func clearEnv(t *testing.T) {
+	for _, name := range []string{
+		"PERIL_CONFIG", "PERIL_BROKER_URL", "PERIL_VHOST", "PERIL_TLS", "PERIL_TLS_INSECURE",
+		"PERIL_TLS_CA", "PERIL_TLS_CERT", "PERIL_TLS_KEY", "PERIL_TLS_SERVER_NAME",
+		"PERIL_EXCHANGE_PREFIX", "PERIL_QUEUE_PREFIX", "PERIL_LOG_FILE", "PERIL_FEATURES",
+	} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func TestLoaderLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: Default(),
+		},
+		{
+			name: "file",
+			file: `{"BrokerURL": "amqp://file:5672/", "LogFile": "file.log", "Features": {"Chat": false, "Dashboard": true}}`,
+			want: Config{BrokerURL: "amqp://file:5672/", LogFile: "file.log", Features: Features{Dashboard: true, AdminAPI: true}},
+		},
+		{
+			name: "env over file",
+			file: `{"BrokerURL": "amqp://file:5672/", "LogFile": "file.log"}`,
+			env:  map[string]string{"PERIL_BROKER_URL": "amqp://env:5672/", "PERIL_FEATURES": "-admin"},
+			want: Config{BrokerURL: "amqp://env:5672/", LogFile: "file.log", Features: Features{Dashboard: true, Chat: true}},
+		},
+		{
+			name: "flags over env",
+			file: `{"BrokerURL": "amqp://file:5672/", "LogFile": "file.log", "QueuePrefix": "file."}`,
+			env:  map[string]string{"PERIL_BROKER_URL": "amqp://env:5672/", "PERIL_LOG_FILE": "env.log"},
+			args: []string{"-broker", "amqp://flag:5672/", "-features", "-chat"},
+			want: Config{BrokerURL: "amqp://flag:5672/", LogFile: "env.log", QueuePrefix: "file.", Features: Features{Dashboard: true, AdminAPI: true}},
+		},
+		{
+			name: "flag defaults don't hide the env",
+			env:  map[string]string{"PERIL_LOG_FILE": "env.log"},
+			args: []string{"-vhost", "peril"},
+			want: Config{BrokerURL: DefaultBrokerURL, VHost: "peril", LogFile: "env.log", Features: Default().Features},
+		},
+		{
+			name:    "bad env bool",
+			env:     map[string]string{"PERIL_TLS": "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown feature",
+			args:    []string{"-features", "radar"},
+			wantErr: true,
+		},
+		{
+			name:    "missing config named by the env",
+			env:     map[string]string{"PERIL_CONFIG": filepath.Join(os.TempDir(), "peril-missing.json")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			args := tt.args
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "peril.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				args = append([]string{"-config", path}, args...)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			loader := Flags(fs)
+			if err := fs.Parse(args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loader.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ChatChannel string
+
+const (
+	ChatGlobal   ChatChannel = "global"
+	ChatAlliance ChatChannel = "alliance"
+	ChatDirect   ChatChannel = "direct"
+)
+
+type ChatMessage struct {
+	Game    string
+	Channel ChatChannel
+	From    string
+	// To is empty for global messages
+	To     string
+	Text   string
+	SentAt time.Time
+}
+
+type ChatHistoryRequest struct {
+	Game  string
+	Limit int
+}
+
+func (gs *GameState) newChatMessage(channel ChatChannel, to string, words []string) ChatMessage {
+	return ChatMessage{
+		Game:    gs.GetGame(),
+		Channel: channel,
+		From:    gs.GetUsername(),
+		To:      to,
+		Text:    strings.Join(words, " "),
+		SentAt:  time.Now(),
+	}
+}
+
+func (gs *GameState) CommandSay(words []string) (ChatMessage, error) {
+	if gs.IsMuted() {
+		return ChatMessage{}, errMuted
+	}
+	if len(words) < 2 {
+		return ChatMessage{}, errors.New("usage: say <message>")
+	}
+	return gs.newChatMessage(ChatGlobal, "", words[1:]), nil
+}
+
+func (gs *GameState) CommandWhisper(words []string) (ChatMessage, error) {
+	if gs.IsMuted() {
+		return ChatMessage{}, errMuted
+	}
+	if len(words) < 3 {
+		return ChatMessage{}, errors.New("usage: whisper <player> <message>")
+	}
+	if words[1] == gs.GetUsername() {
+		return ChatMessage{}, errors.New("error: you can not whisper to yourself")
+	}
+	return gs.newChatMessage(ChatDirect, words[1], words[2:]), nil
+}
+
+// CommandAlly returns a copy of the message for every ally
+func (gs *GameState) CommandAlly(words []string) ([]ChatMessage, error) {
+	if gs.IsMuted() {
+		return nil, errMuted
+	}
+	if len(words) < 2 {
+		return nil, errors.New("usage: ally <message>")
+	}
+	allies := gs.GetAllies()
+	if len(allies) == 0 {
+		return nil, errors.New("error: you don't have any allies")
+	}
+	msgs := []ChatMessage{}
+	for _, ally := range allies {
+		msgs = append(msgs, gs.newChatMessage(ChatAlliance, ally, words[1:]))
+	}
+	return msgs, nil
+}
+
+func (gs *GameState) HandleChat(msg ChatMessage) {
+	if msg.From == gs.GetUsername() {
+		return
+	}
+	gs.println()
+	gs.println(FormatChat(msg))
+}
+
+func PrintChat(msg ChatMessage) {
+	fmt.Println(FormatChat(msg))
+}
+
+func FormatChat(msg ChatMessage) string {
+	switch msg.Channel {
+	case ChatDirect:
+		return fmt.Sprintf("%s [whisper] %s -> %s: %s", msg.SentAt.Format(time.Kitchen), msg.From, msg.To, msg.Text)
+	case ChatAlliance:
+		return fmt.Sprintf("%s [alliance] %s: %s", msg.SentAt.Format(time.Kitchen), msg.From, msg.Text)
+	default:
+		return fmt.Sprintf("%s [global] %s: %s", msg.SentAt.Format(time.Kitchen), msg.From, msg.Text)
+	}
+}
+
+// Public tells whether every player may read a message. Anyone can ask for the
+// history under any name, so it only replays public messages.
+func (msg ChatMessage) Public() bool {
+	return msg.Channel == ChatGlobal
+}
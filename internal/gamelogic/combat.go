@@ -0,0 +1,204 @@
+package gamelogic
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+const (
+	CombatPower = "power"
+	CombatDice  = "dice"
+)
+
+type BattleOutcome int
+
+const (
+	BattleAttackerWon BattleOutcome = iota
+	BattleDefenderWon
+	BattleDraw
+)
+
+type BattleRound struct {
+	AttackerRolls  []int
+	DefenderRolls  []int
+	AttackerLosses int
+	DefenderLosses int
+}
+
+type BattleResult struct {
+	Outcome        BattleOutcome
+	AttackerPower  int
+	DefenderPower  int
+	AttackerLosses []Unit
+	DefenderLosses []Unit
+	Rounds         []BattleRound
+}
+
+// A CombatResolver decides the result of a battle in a single location. It
+// must be deterministic for a given seed, every participant of a war resolves
+// it on their own and they all have to agree on the result.
+type CombatResolver interface {
+	Name() string
+	Resolve(attackers, defenders []Unit, rules Ruleset, seed int64) BattleResult
+}
+
+func GetCombatResolver(name string) (CombatResolver, error) {
+	switch name {
+	case "", CombatPower:
+		return PowerResolver{}, nil
+	case CombatDice:
+		return DiceResolver{MaxRounds: 50}, nil
+	default:
+		return nil, fmt.Errorf("error: %s is not a valid combat model", name)
+	}
+}
+
+// PowerResolver compares the summed power of both sides, the strongest side
+// wins and the loser is wiped out. A draw wipes out everyone.
+type PowerResolver struct{}
+
+func (PowerResolver) Name() string {
+	return CombatPower
+}
+
+func (PowerResolver) Resolve(attackers, defenders []Unit, rules Ruleset, seed int64) BattleResult {
+	result := BattleResult{
+		AttackerPower: unitsToPowerLevel(attackers, defenders, rules),
+		DefenderPower: unitsToPowerLevel(defenders, attackers, rules),
+	}
+	switch {
+	case result.AttackerPower > result.DefenderPower:
+		result.Outcome = BattleAttackerWon
+		result.DefenderLosses = defenders
+	case result.DefenderPower > result.AttackerPower:
+		result.Outcome = BattleDefenderWon
+		result.AttackerLosses = attackers
+	default:
+		result.Outcome = BattleDraw
+		result.AttackerLosses = attackers
+		result.DefenderLosses = defenders
+	}
+	return result
+}
+
+// DiceResolver fights Risk-style rounds: the attacker rolls a die for up to
+// three of its strongest units and the defender for up to two. Dice are paired
+// from highest to lowest, the lower die loses its unit and the defender wins
+// ties. Each unit adds a fifth of its power to its roll. The battle goes on
+// until one side is wiped out, or it's a draw after MaxRounds.
+type DiceResolver struct {
+	MaxRounds int
+}
+
+func (DiceResolver) Name() string {
+	return CombatDice
+}
+
+type fighter struct {
+	unit  Unit
+	bonus int
+}
+
+func (d DiceResolver) Resolve(attackers, defenders []Unit, rules Ruleset, seed int64) BattleResult {
+	rng := rand.New(rand.NewSource(seed))
+	result := BattleResult{
+		AttackerPower: unitsToPowerLevel(attackers, defenders, rules),
+		DefenderPower: unitsToPowerLevel(defenders, attackers, rules),
+	}
+	att := toFighters(attackers, defenders, rules)
+	def := toFighters(defenders, attackers, rules)
+
+	for round := 0; round < d.MaxRounds && len(att) > 0 && len(def) > 0; round++ {
+		sortFighters(att)
+		sortFighters(def)
+		attRolls := rollDice(rng, att, 3)
+		defRolls := rollDice(rng, def, 2)
+		br := BattleRound{AttackerRolls: attRolls, DefenderRolls: defRolls}
+
+		deadAtt := map[int]bool{}
+		deadDef := map[int]bool{}
+		for i := 0; i < len(attRolls) && i < len(defRolls); i++ {
+			if attRolls[i] > defRolls[i] {
+				deadDef[i] = true
+				br.DefenderLosses++
+			} else {
+				deadAtt[i] = true
+				br.AttackerLosses++
+			}
+		}
+		att, result.AttackerLosses = removeFighters(att, deadAtt, result.AttackerLosses)
+		def, result.DefenderLosses = removeFighters(def, deadDef, result.DefenderLosses)
+		result.Rounds = append(result.Rounds, br)
+	}
+
+	switch {
+	case len(def) == 0 && len(att) > 0:
+		result.Outcome = BattleAttackerWon
+	case len(att) == 0 && len(def) > 0:
+		result.Outcome = BattleDefenderWon
+	default:
+		result.Outcome = BattleDraw
+	}
+	return result
+}
+
+func toFighters(units []Unit, enemies []Unit, rules Ruleset) []fighter {
+	fighters := []fighter{}
+	for _, u := range units {
+		fighters = append(fighters, fighter{
+			unit:  u,
+			bonus: unitsToPowerLevel([]Unit{u}, enemies, rules) / 5,
+		})
+	}
+	return fighters
+}
+
+// sortFighters puts the strongest units first, so the best units roll the dice
+// and the weakest are only sent in once they're gone
+func sortFighters(fighters []fighter) {
+	sort.SliceStable(fighters, func(i, j int) bool {
+		if fighters[i].bonus != fighters[j].bonus {
+			return fighters[i].bonus > fighters[j].bonus
+		}
+		return fighters[i].unit.ID < fighters[j].unit.ID
+	})
+}
+
+// rollDice rolls for the first n fighters and reorders them so the i-th
+// fighter is the one that rolled the i-th highest die
+func rollDice(rng *rand.Rand, fighters []fighter, n int) []int {
+	if n > len(fighters) {
+		n = len(fighters)
+	}
+	rolls := make([]int, n)
+	for i := 0; i < n; i++ {
+		rolls[i] = rng.Intn(6) + 1 + fighters[i].bonus
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return rolls[order[i]] > rolls[order[j]] })
+
+	sortedRolls := make([]int, n)
+	sortedFighters := make([]fighter, n)
+	for i, idx := range order {
+		sortedRolls[i] = rolls[idx]
+		sortedFighters[i] = fighters[idx]
+	}
+	copy(fighters, sortedFighters)
+	return sortedRolls
+}
+
+func removeFighters(fighters []fighter, dead map[int]bool, losses []Unit) ([]fighter, []Unit) {
+	alive := []fighter{}
+	for i, f := range fighters {
+		if dead[i] {
+			losses = append(losses, f.unit)
+			continue
+		}
+		alive = append(alive, f)
+	}
+	return alive, losses
+}
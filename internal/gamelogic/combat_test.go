@@ -0,0 +1,76 @@
+package gamelogic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func army(first int, ranks ...UnitRank) []Unit {
+	units := []Unit{}
+	for i, rank := range ranks {
+		units = append(units, Unit{ID: first + i, Rank: rank, Location: "europe"})
+	}
+	return units
+}
+
+func reversed(units []Unit) []Unit {
+	r := make([]Unit, len(units))
+	for i, u := range units {
+		r[len(units)-1-i] = u
+	}
+	return r
+}
+
+func TestDiceResolverIsDeterministic(t *testing.T) {
+	rules := DefaultRuleset()
+	tests := []struct {
+		name      string
+		attackers []Unit
+		defenders []Unit
+		seed      int64
+	}{
+		{"even", army(1, RankInfantry, RankInfantry, RankInfantry), army(1, RankInfantry, RankInfantry, RankInfantry), 1},
+		{"mixed", army(1, RankInfantry, RankCavalry, RankArtillery, RankInfantry), army(10, RankCavalry, RankArtillery), 42},
+		{"outnumbered", army(1, RankInfantry), army(1, RankArtillery, RankArtillery, RankCavalry, RankInfantry), 7},
+		{"negative seed", army(1, RankCavalry, RankCavalry), army(5, RankInfantry, RankInfantry), -3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiceResolver{MaxRounds: 50}
+			want := d.Resolve(tt.attackers, tt.defenders, rules, tt.seed)
+			if got := d.Resolve(tt.attackers, tt.defenders, rules, tt.seed); !reflect.DeepEqual(got, want) {
+				t.Fatalf("second resolve differs:\ngot  %+v\nwant %+v", got, want)
+			}
+			// every player builds the armies from a map, the order can't matter
+			if got := d.Resolve(reversed(tt.attackers), reversed(tt.defenders), rules, tt.seed); !reflect.DeepEqual(got, want) {
+				t.Fatalf("resolve with the units in another order differs:\ngot  %+v\nwant %+v", got, want)
+			}
+			if len(want.AttackerLosses)+len(want.DefenderLosses) == 0 {
+				t.Fatal("nobody died")
+			}
+		})
+	}
+}
+
+func TestDiceResolverOutcomes(t *testing.T) {
+	rules := DefaultRuleset()
+	tests := []struct {
+		name      string
+		resolver  DiceResolver
+		attackers []Unit
+		defenders []Unit
+		want      BattleOutcome
+	}{
+		{"no defenders", DiceResolver{MaxRounds: 50}, army(1, RankInfantry), nil, BattleAttackerWon},
+		{"no attackers", DiceResolver{MaxRounds: 50}, nil, army(1, RankInfantry), BattleDefenderWon},
+		{"out of rounds", DiceResolver{MaxRounds: 0}, army(1, RankInfantry), army(1, RankInfantry), BattleDraw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.resolver.Resolve(tt.attackers, tt.defenders, rules, 1)
+			if got.Outcome != tt.want {
+				t.Errorf("got outcome %v, want %v", got.Outcome, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+type DiplomacyType string
+
+const (
+	DiplomacyPropose DiplomacyType = "propose"
+	DiplomacyAccept  DiplomacyType = "accept"
+	DiplomacyBreak   DiplomacyType = "break"
+	// DiplomacyStatus keeps allies up to date with the sender's units
+	DiplomacyStatus DiplomacyType = "status"
+)
+
+type DiplomacyMessage struct {
+	Game string
+	Type DiplomacyType
+	From string
+	To   string
+	// Player is the sender's snapshot, allies need it to fight together
+	Player Player
+}
+
+func (gs *GameState) IsAlly(username string) bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	_, ok := gs.allies[username]
+	return ok
+}
+
+func (gs *GameState) GetAllies() []string {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	allies := []string{}
+	for name := range gs.allies {
+		allies = append(allies, name)
+	}
+	sort.Strings(allies)
+	return allies
+}
+
+// GetAllySnaps returns the last known units of every ally
+func (gs *GameState) GetAllySnaps() []Player {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	snaps := []Player{}
+	for _, p := range gs.allies {
+		snaps = append(snaps, p)
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Username < snaps[j].Username })
+	return snaps
+}
+
+// updateAlly keeps track of the units of an ally as they move around
+func (gs *GameState) updateAlly(p Player) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if _, ok := gs.allies[p.Username]; ok {
+		gs.allies[p.Username] = p
+	}
+}
+
+// AllyStatuses returns a status message for every ally, to be sent whenever
+// the player's units change
+func (gs *GameState) AllyStatuses() []DiplomacyMessage {
+	msgs := []DiplomacyMessage{}
+	for _, ally := range gs.GetAllies() {
+		msgs = append(msgs, gs.newDiplomacyMessage(DiplomacyStatus, ally))
+	}
+	return msgs
+}
+
+func (gs *GameState) newDiplomacyMessage(t DiplomacyType, to string) DiplomacyMessage {
+	return DiplomacyMessage{
+		Game:   gs.GetGame(),
+		Type:   t,
+		From:   gs.GetUsername(),
+		To:     to,
+		Player: gs.GetPlayerSnap(),
+	}
+}
+
+func (gs *GameState) CommandPropose(words []string) (DiplomacyMessage, error) {
+	if len(words) < 2 {
+		return DiplomacyMessage{}, errors.New("usage: propose <player>")
+	}
+	to := words[1]
+	if to == gs.GetUsername() {
+		return DiplomacyMessage{}, errors.New("error: you can not ally with yourself")
+	}
+	if gs.IsAlly(to) {
+		return DiplomacyMessage{}, fmt.Errorf("error: you are already allied with %s", to)
+	}
+	gs.mu.Lock()
+	gs.proposals[to] = true
+	gs.mu.Unlock()
+	gs.printf("You proposed an alliance to %s\n", to)
+	return gs.newDiplomacyMessage(DiplomacyPropose, to), nil
+}
+
+func (gs *GameState) CommandAccept(words []string) (DiplomacyMessage, error) {
+	if len(words) < 2 {
+		return DiplomacyMessage{}, errors.New("usage: accept <player>")
+	}
+	from := words[1]
+	gs.mu.Lock()
+	proposal, ok := gs.proposed[from]
+	if ok {
+		delete(gs.proposed, from)
+		gs.allies[from] = proposal.Player
+	}
+	gs.mu.Unlock()
+	if !ok {
+		return DiplomacyMessage{}, fmt.Errorf("error: %s has not proposed an alliance", from)
+	}
+	gs.printf("You are now allied with %s\n", from)
+	return gs.newDiplomacyMessage(DiplomacyAccept, from), nil
+}
+
+func (gs *GameState) CommandBreak(words []string) (DiplomacyMessage, error) {
+	if len(words) < 2 {
+		return DiplomacyMessage{}, errors.New("usage: break <player>")
+	}
+	ally := words[1]
+	if !gs.IsAlly(ally) {
+		return DiplomacyMessage{}, fmt.Errorf("error: you are not allied with %s", ally)
+	}
+	gs.mu.Lock()
+	delete(gs.allies, ally)
+	gs.mu.Unlock()
+	gs.printf("You broke your alliance with %s\n", ally)
+	return gs.newDiplomacyMessage(DiplomacyBreak, ally), nil
+}
+
+func (gs *GameState) HandleDiplomacy(msg DiplomacyMessage) {
+	if msg.Type == DiplomacyStatus {
+		gs.updateAlly(msg.Player)
+		return
+	}
+	defer gs.println("------------------------")
+	gs.println()
+	gs.println("==== Diplomacy ====")
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	switch msg.Type {
+	case DiplomacyPropose:
+		gs.proposed[msg.From] = msg
+		gs.printf("%s proposes an alliance, type accept %s to become allies.\n", msg.From, msg.From)
+	case DiplomacyAccept:
+		if !gs.proposals[msg.From] {
+			gs.printf("%s accepted an alliance you never proposed.\n", msg.From)
+			return
+		}
+		delete(gs.proposals, msg.From)
+		gs.allies[msg.From] = msg.Player
+		gs.printf("%s accepted your alliance, you are now allies!\n", msg.From)
+	case DiplomacyBreak:
+		delete(gs.allies, msg.From)
+		gs.printf("%s broke your alliance!\n", msg.From)
+	default:
+		gs.printf("Unknown diplomacy message from %s.\n", msg.From)
+	}
+}
@@ -0,0 +1,82 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type EconomyRules struct {
+	StartingFunds int
+	// TickInterval is how often the server pays the income of every region,
+	// 0 disables income
+	TickInterval Duration
+	// Income is paid every tick to the player controlling the region
+	Income map[Location]int
+}
+
+type IncomeTick struct {
+	Game   string
+	Tick   int
+	Income map[string]int
+}
+
+// Income is what a player earns in a tick from the regions they control
+func (w *World) Income(username string, rules Ruleset) int {
+	income := 0
+	for _, loc := range w.RegionsControlled(username) {
+		income += rules.Economy.Income[loc]
+	}
+	return income
+}
+
+func (gs *GameState) HandleIncome(tick IncomeTick) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if tick.Tick <= gs.lastTick {
+		return
+	}
+	if gs.lastTick != 0 && tick.Tick > gs.lastTick+1 {
+		gs.printf("Missed %v income tick(s), your balance may be behind.\n", tick.Tick-gs.lastTick-1)
+	}
+	gs.lastTick = tick.Tick
+	income := tick.Income[gs.Player.Username]
+	gs.Funds += income
+	if income > 0 {
+		gs.printf("Your regions earned %v gold, you now have %v.\n", income, gs.Funds)
+	}
+}
+
+func (gs *GameState) GetFunds() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.Funds
+}
+
+func (gs *GameState) spend(cost int) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.Funds < cost {
+		return fmt.Errorf("error: that costs %v gold but you only have %v", cost, gs.Funds)
+	}
+	gs.Funds -= cost
+	return nil
+}
+
+func (r Ruleset) validateEconomy() error {
+	if r.Economy.StartingFunds < 0 {
+		return errors.New("starting funds can not be negative")
+	}
+	if r.Economy.TickInterval.Duration != 0 && r.Economy.TickInterval.Duration < time.Second {
+		return errors.New("the income tick interval must be at least a second")
+	}
+	for loc, income := range r.Economy.Income {
+		if _, ok := getAllLocations()[loc]; !ok {
+			return fmt.Errorf("%s is not a valid location", loc)
+		}
+		if income < 0 {
+			return fmt.Errorf("the income of %s can not be negative", loc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package gamelogic
+
+// VisibleLocations returns the regions a player can see: the ones where they
+// have units and their neighbors
+func (w *World) VisibleLocations(username string) map[Location]bool {
+	visible := map[Location]bool{}
+	p, ok := w.GetPlayer(username)
+	if !ok {
+		return visible
+	}
+	for _, u := range p.Units {
+		if u.InTransit() {
+			continue
+		}
+		visible[u.Location] = true
+		for _, n := range GetNeighbors(u.Location) {
+			visible[n] = true
+		}
+	}
+	return visible
+}
+
+// units on their way are seen both where they left from and where they are
+// going
+func unitVisible(u Unit, visible map[Location]bool) bool {
+	return visible[u.Location] || (u.InTransit() && visible[u.Destination])
+}
+
+func filterUnits(units []Unit, visible map[Location]bool) []Unit {
+	filtered := []Unit{}
+	for _, u := range units {
+		if unitVisible(u, visible) {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
+
+func filterPlayer(p Player, visible map[Location]bool) Player {
+	units := map[int]Unit{}
+	for id, u := range p.Units {
+		if unitVisible(u, visible) {
+			units[id] = u
+		}
+	}
+	return Player{Username: p.Username, Units: units}
+}
+
+// FilterMove strips a move down to the units that can be seen from the given
+// locations, it returns false when none can
+func FilterMove(move ArmyMove, visible map[Location]bool) (ArmyMove, bool) {
+	units := filterUnits(move.Units, visible)
+	if len(units) == 0 {
+		return ArmyMove{}, false
+	}
+	move.Units = units
+	move.Player = filterPlayer(move.Player, visible)
+	return move, true
+}
+
+// StripWar keeps only the units in the location of the war, so declaring one
+// doesn't give away the rest of the players' armies
+func StripWar(rw RecognitionOfWar) RecognitionOfWar {
+	rw.Attacker = unitsIn(rw.Attacker, rw.Location)
+	rw.Defender = unitsIn(rw.Defender, rw.Location)
+	allies := []Player{}
+	for _, ally := range rw.AttackerAllies {
+		ally = unitsIn(ally, rw.Location)
+		if len(ally.Units) > 0 {
+			allies = append(allies, ally)
+		}
+	}
+	rw.AttackerAllies = allies
+	return rw
+}
+
+func unitsIn(p Player, loc Location) Player {
+	units := map[int]Unit{}
+	for id, u := range p.Units {
+		if u.Location == loc && !u.InTransit() {
+			units[id] = u
+		}
+	}
+	return Player{Username: p.Username, Units: units}
+}
+
+func FilterArrival(arrival ArmyArrival, visible map[Location]bool) (ArmyArrival, bool) {
+	if !visible[arrival.Location] {
+		return ArmyArrival{}, false
+	}
+	arrival.Units = filterUnits(arrival.Units, visible)
+	arrival.Player = filterPlayer(arrival.Player, visible)
+	return arrival, true
+}
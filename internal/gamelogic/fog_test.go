@@ -0,0 +1,43 @@
+package gamelogic
+
+import "testing"
+
+func TestStripWar(t *testing.T) {
+	rw := StripWar(RecognitionOfWar{
+		Attacker: Player{Username: "alice", Units: map[int]Unit{
+			1: {ID: 1, Location: "europe"},
+			2: {ID: 2, Location: "asia"},
+			3: {ID: 3, Location: "africa", Destination: "europe"},
+		}},
+		AttackerAllies: []Player{
+			{Username: "bob", Units: map[int]Unit{1: {ID: 1, Location: "europe"}}},
+			{Username: "carol", Units: map[int]Unit{1: {ID: 1, Location: "americas"}}},
+		},
+		Defender: Player{Username: "mallory", Units: map[int]Unit{
+			1: {ID: 1, Location: "europe"},
+			2: {ID: 2, Location: "australia"},
+		}},
+		Location: "europe",
+	})
+	tests := []struct {
+		name string
+		got  Player
+		want []int
+	}{
+		{"attacker", rw.Attacker, []int{1}},
+		{"defender", rw.Defender, []int{1}},
+	}
+	for _, tt := range tests {
+		if len(tt.got.Units) != len(tt.want) {
+			t.Errorf("%s kept %v units, want %v", tt.name, len(tt.got.Units), len(tt.want))
+		}
+		for _, id := range tt.want {
+			if _, ok := tt.got.Units[id]; !ok {
+				t.Errorf("%s lost unit %v", tt.name, id)
+			}
+		}
+	}
+	if len(rw.AttackerAllies) != 1 || rw.AttackerAllies[0].Username != "bob" {
+		t.Errorf("got allies %+v, want only bob", rw.AttackerAllies)
+	}
+}
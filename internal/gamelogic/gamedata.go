@@ -1,5 +1,7 @@
 package gamelogic
 
+import "time"
+
 type Player struct {
 	Username string
 	Units    map[int]Unit
@@ -14,32 +16,49 @@ const (
 )
 
 type Unit struct {
-	ID       int
-	Rank     UnitRank
-	Location Location
+	ID          int
+	Rank        UnitRank
+	Location    Location
+	Destination Location
+	ArrivesAt   time.Time
+}
+
+func (u Unit) InTransit() bool {
+	return u.Destination != ""
 }
 
 type ArmyMove struct {
+	Game       string
 	Player     Player
 	Units      []Unit
 	ToLocation Location
+	ArrivesAt  time.Time
+}
+
+type ArmyArrival struct {
+	Game     string
+	Player   Player
+	Units    []Unit
+	Location Location
 }
 
 type RecognitionOfWar struct {
+	Game     string
 	Attacker Player
-	Defender Player
+	// AttackerAllies fight alongside the attacker with their units in the
+	// location
+	AttackerAllies []Player
+	Defender       Player
+	// Location is where the battle is fought, a war is declared for each
+	// location the players share
+	Location Location
+	// Combat and Seed let every participant replay exactly the same battle
+	Combat string
+	Seed   int64
 }
 
 type Location string
 
-func getAllRanks() map[UnitRank]struct{} {
-	return map[UnitRank]struct{}{
-		RankInfantry:  {},
-		RankCavalry:   {},
-		RankArtillery: {},
-	}
-}
-
 func getAllLocations() map[Location]struct{} {
 	return map[Location]struct{}{
 		"americas":   {},
@@ -1,15 +1,20 @@
 package gamelogic
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/lineedit"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
 )
 
-func PrintClientHelp() {
+// PrintClientHelp lists the commands, the wait and expect directives only
+// make sense in a script
+func PrintClientHelp(scripted bool) {
 	fmt.Println("Possible commands:")
 	fmt.Println("* move <location> <unitID> <unitID> <unitID>...")
 	fmt.Println("    example:")
@@ -18,9 +23,23 @@ func PrintClientHelp() {
 	fmt.Println("    example:")
 	fmt.Println("    spawn europe infantry")
 	fmt.Println("* status")
+	fmt.Println("* propose <player>")
+	fmt.Println("* accept <player>")
+	fmt.Println("* break <player>")
+	fmt.Println("* say <message>")
+	fmt.Println("* whisper <player> <message>")
+	fmt.Println("* ally <message>")
+	fmt.Println("* history")
+	fmt.Println("* stats [player]")
 	fmt.Println("* spam <n>")
 	fmt.Println("    example:")
 	fmt.Println("    spam 5")
+	if scripted {
+		fmt.Println("* wait <duration>")
+		fmt.Println("* expect <event> [timeout]")
+		fmt.Println("    example:")
+		fmt.Println("    expect pause 10s")
+	}
 	fmt.Println("* quit")
 	fmt.Println("* help")
 }
@@ -34,27 +53,90 @@ func ClientWelcome() (string, error) {
 	}
 	username := words[0]
 	fmt.Printf("Welcome, %s!\n", username)
-	PrintClientHelp()
 	return username, nil
 }
 
+func PrintJoinHelp() {
+	fmt.Println("Pick a game to play:")
+	fmt.Println("* join <game>")
+	fmt.Println("    example:")
+	fmt.Println("    join main")
+	fmt.Println("* quit")
+}
+
 func PrintServerHelp() {
 	fmt.Println("Possible commands:")
-	fmt.Println("* pause")
-	fmt.Println("* resume")
+	fmt.Println("* pause [at <hh:mm>] [for <duration>] [game]")
+	fmt.Println("* resume [game]")
+	fmt.Println("* maintenance [<hh:mm> <duration> [game] | clear]")
+	fmt.Println("* create <game> [turns]")
+	fmt.Println("* games")
+	fmt.Println("* players [game]")
+	fmt.Println("* announce <message>")
+	fmt.Println("* close <game>")
+	fmt.Println("* leaderboard")
+	fmt.Println("* kick <player>")
+	fmt.Println("* ban <player> [duration]")
+	fmt.Println("* unban <player>")
+	fmt.Println("* bans")
+	fmt.Println("* mute <player>")
+	fmt.Println("* unmute <player>")
 	fmt.Println("* quit")
 	fmt.Println("* help")
 }
 
+func PrintGames(games []routing.GameInfo) {
+	if len(games) == 0 {
+		fmt.Println("There are no games.")
+		return
+	}
+	fmt.Println("Games:")
+	for _, g := range games {
+		fmt.Printf("* %s: %v player(s) %v, created %s\n", g.ID, len(g.Players), g.Players, g.CreatedAt.Format(time.Kitchen))
+	}
+}
+
+// input is shared by every GetInput call, a new reader each time would lose
+// whatever the last one had buffered
+var input = lineedit.NewReader(os.Stdin, stdout{})
+
+// stdout writes to whatever os.Stdout is at the time, the client moves it
+// to stderr in JSON mode
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+// LoadInputHistory brings back the commands typed in earlier sessions and
+// saves the new ones in path
+func LoadInputHistory(path string) error {
+	return input.LoadHistory(path)
+}
+
+// SetInputCompleter sets what tab completes at the prompt
+func SetInputCompleter(c lineedit.Completer) {
+	input.SetCompleter(c)
+}
+
+// CloseInput hands the terminal back the way it was found, call it before
+// exiting
+func CloseInput() {
+	input.Close()
+}
+
 func GetInput() []string {
-	fmt.Print("> ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanned := scanner.Scan()
-	if !scanned {
+	line, err := input.ReadLine("> ")
+	if errors.Is(err, lineedit.ErrInterrupted) {
+		// ctrl-c quits like it did before the terminal was in our hands
+		input.Close()
+		p, _ := os.FindProcess(os.Getpid())
+		p.Signal(os.Interrupt)
+		return []string{}
+	}
+	if err != nil {
 		return nil
 	}
-	line := scanner.Text()
-	line = strings.TrimSpace(line)
 	return strings.Fields(line)
 }
 
@@ -77,16 +159,40 @@ func PrintQuit() {
 }
 
 func (gs *GameState) CommandStatus() {
-	if gs.isPaused() {
-		fmt.Println("The game is paused.")
+	if gs.isClosed() {
+		gs.printf("The game %s is over.\n", gs.GetGame())
+	}
+	if gs.IsPaused() {
+		reason, resumeAt := gs.GetPauseInfo()
+		if reason != "" {
+			gs.printf("The game is paused: %s.\n", reason)
+		} else {
+			gs.println("The game is paused.")
+		}
+		if !resumeAt.IsZero() {
+			gs.printf("It resumes in %v.\n", max(time.Until(resumeAt), 0).Round(time.Second))
+		}
 		return
 	} else {
-		fmt.Println("The game is not paused.")
+		gs.println("The game is not paused.")
+	}
+
+	if gs.IsTurnBased() {
+		turn, deadline := gs.GetTurn()
+		gs.printf("It is turn %v, it ends in %v. You have %v order(s) queued.\n", turn, time.Until(deadline).Round(time.Second), gs.queuedOrders())
 	}
 
 	p := gs.GetPlayerSnap()
-	fmt.Printf("You are %s, and you have %d units.\n", p.Username, len(p.Units))
+	gs.printf("You are %s, and you have %d units.\n", p.Username, len(p.Units))
+	gs.printf("You have %v gold.\n", gs.GetFunds())
+	if allies := gs.GetAllies(); len(allies) > 0 {
+		gs.printf("You are allied with %v.\n", allies)
+	}
 	for _, unit := range p.Units {
-		fmt.Printf("* %v: %v, %v\n", unit.ID, unit.Location, unit.Rank)
+		if unit.InTransit() {
+			gs.printf("* %v: %v -> %v (arrives in %v), %v\n", unit.ID, unit.Location, unit.Destination, time.Until(unit.ArrivesAt).Round(time.Second), unit.Rank)
+			continue
+		}
+		gs.printf("* %v: %v, %v\n", unit.ID, unit.Location, unit.Rank)
 	}
 }
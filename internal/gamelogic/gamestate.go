@@ -1,13 +1,44 @@
 package gamelogic
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type GameState struct {
-	Player Player
-	Paused bool
-	mu     *sync.RWMutex
+	Player    Player
+	Game      string
+	Paused    bool
+	Closed    bool
+	Muted     bool
+	Funds     int
+	TurnBased bool
+	Turn      int
+	Deadline  time.Time
+	// PauseReason and ResumeAt are set by the server when it pauses the game
+	PauseReason string
+	ResumeAt    time.Time
+	rules       Ruleset
+	lastTick    int
+	// lastUnitID only grows, so the IDs of dead units are never reused
+	lastUnitID int
+	// stateVersion is the version of the last playing state we applied
+	stateVersion int
+	orders       [][]string
+	// allies maps every ally to their last known units, proposals are the
+	// alliances we offered and proposed the ones offered to us
+	allies    map[string]Player
+	proposals map[string]bool
+	proposed  map[string]DiplomacyMessage
+	// out is where the game talks to the player, the terminal by default.
+	// It holds an output and is read without mu, the handlers print while
+	// holding it.
+	out *atomic.Value
+	mu  *sync.RWMutex
 }
 
 func NewGameState(username string) *GameState {
@@ -16,11 +47,42 @@ func NewGameState(username string) *GameState {
 			Username: username,
 			Units:    map[int]Unit{},
 		},
-		Paused: false,
-		mu:     &sync.RWMutex{},
+		Paused:    false,
+		Funds:     DefaultRuleset().Economy.StartingFunds,
+		rules:     DefaultRuleset(),
+		allies:    map[string]Player{},
+		proposals: map[string]bool{},
+		proposed:  map[string]DiplomacyMessage{},
+		out:       outputTo(os.Stdout),
+		mu:        &sync.RWMutex{},
 	}
 }
 
+// output wraps the writer so atomic.Value always stores the same type
+type output struct {
+	w io.Writer
+}
+
+func outputTo(w io.Writer) *atomic.Value {
+	out := &atomic.Value{}
+	out.Store(output{w})
+	return out
+}
+
+// SetOutput sends everything the game has to say to the player to w instead
+// of the terminal
+func (gs *GameState) SetOutput(w io.Writer) {
+	gs.out.Store(output{w})
+}
+
+func (gs *GameState) printf(format string, a ...any) {
+	fmt.Fprintf(gs.out.Load().(output).w, format, a...)
+}
+
+func (gs *GameState) println(a ...any) {
+	fmt.Fprintln(gs.out.Load().(output).w, a...)
+}
+
 func (gs *GameState) resumeGame() {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
@@ -33,16 +95,65 @@ func (gs *GameState) pauseGame() {
 	gs.Paused = true
 }
 
-func (gs *GameState) isPaused() bool {
+func (gs *GameState) setPauseInfo(reason string, resumeAt time.Time) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.PauseReason = reason
+	gs.ResumeAt = resumeAt
+}
+
+func (gs *GameState) GetPauseInfo() (string, time.Time) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.PauseReason, gs.ResumeAt
+}
+
+func (gs *GameState) IsPaused() bool {
 	gs.mu.RLock()
 	defer gs.mu.RUnlock()
 	return gs.Paused
 }
 
+func (gs *GameState) closeGame() {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.Closed = true
+}
+
+func (gs *GameState) isClosed() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.Closed
+}
+
+func (gs *GameState) SetGame(id string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.Game = id
+}
+
+func (gs *GameState) GetGame() string {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.Game
+}
+
 func (gs *GameState) addUnit(u Unit) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
 	gs.Player.Units[u.ID] = u
+	if u.ID > gs.lastUnitID {
+		gs.lastUnitID = u.ID
+	}
+}
+
+// nextUnitID can't look at the units we have left, IDs would be reused once
+// the newest ones have been killed
+func (gs *GameState) nextUnitID() int {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.lastUnitID++
+	return gs.lastUnitID
 }
 
 func (gs *GameState) removeUnitsInLocation(loc Location) {
@@ -55,6 +166,14 @@ func (gs *GameState) removeUnitsInLocation(loc Location) {
 	}
 }
 
+func (gs *GameState) removeUnits(units []Unit) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for _, u := range units {
+		delete(gs.Player.Units, u.ID)
+	}
+}
+
 func (gs *GameState) UpdateUnit(u Unit) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
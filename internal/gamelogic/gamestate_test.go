@@ -0,0 +1,77 @@
+package gamelogic
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// the handlers print while holding the state's lock, printing must not take
+// it again
+func TestPrintWhileLocked(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(gs *GameState)
+	}{
+		{"income", func(gs *GameState) {
+			gs.HandleIncome(IncomeTick{Tick: 3, Income: map[string]int{"alice": 5}})
+		}},
+		{"diplomacy", func(gs *GameState) {
+			gs.HandleDiplomacy(DiplomacyMessage{Type: DiplomacyPropose, From: "bob", To: "alice"})
+		}},
+		{"queued order", func(gs *GameState) {
+			gs.QueueOrder([]string{"spawn", "europe", "infantry"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := NewGameState("alice")
+			var out bytes.Buffer
+			gs.SetOutput(&out)
+			done := make(chan struct{})
+			go func() {
+				tt.run(gs)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("deadlocked")
+			}
+			if out.Len() == 0 {
+				t.Error("nothing was printed")
+			}
+		})
+	}
+}
+
+func TestUnitIDsAreNotReused(t *testing.T) {
+	gs := NewGameState("alice")
+	first := gs.nextUnitID()
+	gs.addUnit(Unit{ID: first, Rank: RankInfantry, Location: "europe"})
+	second := gs.nextUnitID()
+	gs.addUnit(Unit{ID: second, Rank: RankInfantry, Location: "europe"})
+	gs.removeUnits([]Unit{{ID: second}})
+	if third := gs.nextUnitID(); third == first || third == second {
+		t.Errorf("got unit ID %v again after %v and %v", third, first, second)
+	}
+}
+
+func TestSetRulesetKeepsFunds(t *testing.T) {
+	gs := NewGameState("alice")
+	gs.SetOutput(&bytes.Buffer{})
+	rules := DefaultRuleset()
+	rules.Economy.StartingFunds = 50
+	gs.JoinWith(rules)
+	if err := gs.CommandSpawn([]string{"spawn", "europe", "infantry"}); err != nil {
+		t.Fatal(err)
+	}
+	funds := gs.GetFunds()
+	if funds >= 50 {
+		t.Fatalf("got %v funds after spawning, want less than 50", funds)
+	}
+	gs.SetRuleset(rules)
+	if got := gs.GetFunds(); got != funds {
+		t.Errorf("got %v funds after changing the rules, want %v", got, funds)
+	}
+}
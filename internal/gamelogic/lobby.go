@@ -0,0 +1,14 @@
+package gamelogic
+
+import (
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+func (gs *GameState) HandleGameClosed(gc routing.GameClosed) {
+	defer gs.println("------------------------")
+	gs.println()
+	gs.println("==== Game Closed ====")
+	gs.printf("The game %s is over: %s.\n", gc.Game, gc.Reason)
+	gs.println("You can no longer spawn or move units, type quit to leave.")
+	gs.closeGame()
+}
@@ -9,7 +9,12 @@ import (
 	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
 )
 
-const logsFile = "game.log"
+var logsFile = "game.log"
+
+// SetLogFile makes WriteLog write to path
+func SetLogFile(path string) {
+	logsFile = path
+}
 
 const writeToDiskSleep = 1 * time.Second
 
@@ -24,6 +29,9 @@ func WriteLog(gamelog routing.GameLog) error {
 	defer f.Close()
 
 	str := fmt.Sprintf("%v %v: %v\n", gamelog.CurrentTime.Format(time.RFC3339), gamelog.Username, gamelog.Message)
+	if gamelog.Game != "" {
+		str = fmt.Sprintf("%v [%v] %v: %v\n", gamelog.CurrentTime.Format(time.RFC3339), gamelog.Game, gamelog.Username, gamelog.Message)
+	}
 	_, err = f.WriteString(str)
 	if err != nil {
 		return fmt.Errorf("could not write to logs file: %v", err)
@@ -0,0 +1,52 @@
+package gamelogic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+var errMuted = errors.New("error: you are muted and can not send messages")
+
+func (gs *GameState) SetMuted(muted bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.Muted = muted
+}
+
+func (gs *GameState) IsMuted() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.Muted
+}
+
+// HandleControl obeys a moderation message from the server, it returns true
+// when the player must disconnect
+func (gs *GameState) HandleControl(msg routing.ControlMessage) bool {
+	defer gs.println("------------------------")
+	gs.println()
+	switch msg.Action {
+	case routing.ControlKick:
+		gs.println("==== Kicked ====")
+		gs.printf("%s, disconnecting.\n", msg.Reason)
+		return true
+	case routing.ControlBan:
+		gs.println("==== Banned ====")
+		if msg.Until.IsZero() {
+			gs.println("You are banned from this server, disconnecting.")
+		} else {
+			gs.printf("You are banned from this server for %v, disconnecting.\n", time.Until(msg.Until).Round(time.Second))
+		}
+		return true
+	case routing.ControlMute:
+		gs.println("==== Muted ====")
+		gs.println("You can no longer chat or send game logs.")
+		gs.SetMuted(true)
+	case routing.ControlUnmute:
+		gs.println("==== Unmuted ====")
+		gs.println("You can chat again.")
+		gs.SetMuted(false)
+	}
+	return false
+}
@@ -3,7 +3,9 @@ package gamelogic
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"time"
 )
 
 type MoveOutcome int
@@ -15,42 +17,86 @@ const (
 )
 
 func (gs *GameState) HandleMove(move ArmyMove) MoveOutcome {
-	defer fmt.Println("------------------------")
-	player := gs.GetPlayerSnap()
-
-	fmt.Println()
-	fmt.Println("==== Move Detected ====")
-	fmt.Printf("%s is moving %v unit(s) to %s\n", move.Player.Username, len(move.Units), move.ToLocation)
+	defer gs.println("------------------------")
+	gs.println()
+	gs.println("==== Move Detected ====")
+	gs.printf("%s is moving %v unit(s) to %s\n", move.Player.Username, len(move.Units), move.ToLocation)
 	for _, unit := range move.Units {
-		fmt.Printf("* %v\n", unit.Rank)
+		gs.printf("* %v\n", unit.Rank)
+	}
+	if !move.ArrivesAt.IsZero() {
+		gs.printf("They will arrive in %v\n", time.Until(move.ArrivesAt).Round(time.Second))
+	}
+	return gs.moveOutcome(move.Player)
+}
+
+func (gs *GameState) HandleArrival(arrival ArmyArrival) MoveOutcome {
+	defer gs.println("------------------------")
+	gs.println()
+	gs.println("==== Arrival Detected ====")
+	gs.printf("%v of %s's unit(s) arrived in %s\n", len(arrival.Units), arrival.Player.Username, arrival.Location)
+	for _, unit := range arrival.Units {
+		gs.printf("* %v\n", unit.Rank)
 	}
+	return gs.moveOutcome(arrival.Player)
+}
 
-	if player.Username == move.Player.Username {
+// moveOutcome decides what the units of another player mean for us once they
+// moved or arrived
+func (gs *GameState) moveOutcome(other Player) MoveOutcome {
+	player := gs.GetPlayerSnap()
+	if player.Username == other.Username {
 		return MoveOutcomeSamePlayer
 	}
 
-	overlappingLocation := getOverlappingLocation(player, move.Player)
-	if overlappingLocation != "" {
-		fmt.Printf("You have units in %s! You are at war with %s!\n", overlappingLocation, move.Player.Username)
+	if gs.IsAlly(other.Username) {
+		gs.updateAlly(other)
+		gs.printf("%s is your ally, your units can share regions.\n", other.Username)
+		return MoveOutComeSafe
+	}
+
+	overlappingLocations := GetOverlappingLocations(player, other)
+	if len(overlappingLocations) > 0 {
+		for _, loc := range overlappingLocations {
+			gs.printf("You have units in %s! You are at war with %s!\n", loc, other.Username)
+		}
 		return MoveOutcomeMakeWar
 	}
-	fmt.Printf("You are safe from %s's units.\n", move.Player.Username)
+	gs.printf("You are safe from %s's units.\n", other.Username)
 	return MoveOutComeSafe
 }
 
-func getOverlappingLocation(p1 Player, p2 Player) Location {
+// GetOverlappingLocations returns every location where both players have
+// units, sorted by name. Units that are still travelling are not in any
+// location yet, so they can't be part of a battle.
+func GetOverlappingLocations(p1 Player, p2 Player) []Location {
+	found := map[Location]bool{}
 	for _, u1 := range p1.Units {
+		if u1.InTransit() {
+			continue
+		}
 		for _, u2 := range p2.Units {
+			if u2.InTransit() {
+				continue
+			}
 			if u1.Location == u2.Location {
-				return u1.Location
+				found[u1.Location] = true
 			}
 		}
 	}
-	return ""
+	locations := []Location{}
+	for loc := range found {
+		locations = append(locations, loc)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i] < locations[j] })
+	return locations
 }
 
 func (gs *GameState) CommandMove(words []string) (ArmyMove, error) {
-	if gs.isPaused() {
+	if gs.isClosed() {
+		return ArmyMove{}, errors.New("the game is over, you can not move units")
+	}
+	if gs.IsPaused() {
 		return ArmyMove{}, errors.New("the game is paused, you can not move units")
 	}
 	if len(words) < 3 {
@@ -71,20 +117,74 @@ func (gs *GameState) CommandMove(words []string) (ArmyMove, error) {
 		unitIDs = append(unitIDs, unitID)
 	}
 
+	rules := gs.GetRuleset()
+	units := []Unit{}
+	var travelTime time.Duration
 	for _, unitID := range unitIDs {
 		unit, ok := gs.GetUnit(unitID)
 		if !ok {
 			return ArmyMove{}, fmt.Errorf("error: unit with ID %v not found", unitID)
 		}
-		unit.Location = newLocation
-		gs.UpdateUnit(unit)
+		if unit.InTransit() {
+			return ArmyMove{}, fmt.Errorf("error: unit with ID %v is already moving to %s", unitID, unit.Destination)
+		}
+		if unit.Location == newLocation {
+			return ArmyMove{}, fmt.Errorf("error: unit with ID %v is already in %s", unitID, newLocation)
+		}
+		// units go straight to their destination, the shortest route over
+		// the map only decides how long it takes
+		_, duration, err := FindPath(unit.Location, newLocation)
+		if err != nil {
+			return ArmyMove{}, err
+		}
+		if rank, ok := rules.Ranks[unit.Rank]; ok {
+			duration = time.Duration(float64(duration) * rank.Movement)
+		}
+		// the army moves at the pace of its slowest unit
+		if duration > travelTime {
+			travelTime = duration
+		}
+		units = append(units, unit)
+	}
+
+	arrivesAt := time.Now().Add(travelTime)
+	for i := range units {
+		units[i].Destination = newLocation
+		units[i].ArrivesAt = arrivesAt
+		gs.UpdateUnit(units[i])
 	}
 
 	mv := ArmyMove{
+		Game:       gs.GetGame(),
 		ToLocation: newLocation,
-		Units:      gs.getUnitsSnap(),
+		Units:      units,
 		Player:     gs.GetPlayerSnap(),
+		ArrivesAt:  arrivesAt,
 	}
-	fmt.Printf("Moved %v units to %s\n", len(mv.Units), mv.ToLocation)
+	gs.printf("Moving %v units to %s, arriving in %v\n", len(mv.Units), mv.ToLocation, travelTime)
 	return mv, nil
 }
+
+// CompleteMove puts the units of a move that are still on their way into the
+// destination and returns the arrival to announce to the other players.
+func (gs *GameState) CompleteMove(move ArmyMove) ArmyArrival {
+	arrived := []Unit{}
+	for _, u := range move.Units {
+		unit, ok := gs.GetUnit(u.ID)
+		if !ok || unit.Destination != move.ToLocation {
+			continue
+		}
+		unit.Location = move.ToLocation
+		unit.Destination = ""
+		unit.ArrivesAt = time.Time{}
+		gs.UpdateUnit(unit)
+		arrived = append(arrived, unit)
+	}
+	gs.printf("%v units arrived in %s\n", len(arrived), move.ToLocation)
+	return ArmyArrival{
+		Game:     gs.GetGame(),
+		Player:   gs.GetPlayerSnap(),
+		Units:    arrived,
+		Location: move.ToLocation,
+	}
+}
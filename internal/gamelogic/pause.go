@@ -1,19 +1,74 @@
 package gamelogic
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
 )
 
 func (gs *GameState) HandlePause(ps routing.PlayingState) {
-	defer fmt.Println("------------------------")
-	fmt.Println()
+	if !gs.setStateVersion(ps.Version) {
+		return
+	}
+	if ps.Turn > 0 {
+		gs.SetTurnBased(true)
+		gs.setTurn(ps.Turn, ps.Deadline)
+	}
+	gs.setPauseInfo(ps.Reason, ps.ResumeAt)
+	// turn-based games publish their state every turn, there is nothing to
+	// say unless it was paused or resumed
+	if ps.Turn > 0 && ps.IsPaused == gs.IsPaused() {
+		return
+	}
+
+	defer gs.println("------------------------")
+	gs.println()
 	if ps.IsPaused {
-		fmt.Println("==== Pause Detected ====")
+		gs.println("==== Pause Detected ====")
+		if ps.Reason != "" {
+			gs.printf("Reason: %s.\n", ps.Reason)
+		}
+		if !ps.ResumeAt.IsZero() {
+			gs.printf("The game resumes in %v.\n", time.Until(ps.ResumeAt).Round(time.Second))
+		}
 		gs.pauseGame()
 	} else {
-		fmt.Println("==== Resume Detected ====")
+		gs.println("==== Resume Detected ====")
 		gs.resumeGame()
 	}
 }
+
+// SyncPlayingState applies the state the server had when we joined, the pause
+// messages only tell us about what changes after that
+func (gs *GameState) SyncPlayingState(ps routing.PlayingState) {
+	if !gs.setStateVersion(ps.Version) {
+		return
+	}
+	if ps.Turn > 0 {
+		gs.SetTurnBased(true)
+		gs.setTurn(ps.Turn, ps.Deadline)
+	}
+	gs.setPauseInfo(ps.Reason, ps.ResumeAt)
+	if !ps.IsPaused {
+		gs.resumeGame()
+		return
+	}
+	gs.pauseGame()
+	if ps.Reason != "" {
+		gs.printf("The game is paused: %s.\n", ps.Reason)
+	} else {
+		gs.println("The game is paused.")
+	}
+}
+
+// setStateVersion returns false when the state is older than the last one
+// we applied
+func (gs *GameState) setStateVersion(version int) bool {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if version < gs.stateVersion {
+		return false
+	}
+	gs.stateVersion = version
+	return true
+}
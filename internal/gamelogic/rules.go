@@ -0,0 +1,183 @@
+package gamelogic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+type RankRules struct {
+	Power int
+	Cost  int
+	// Movement scales the travel time of a route, so 2 means twice as slow
+	Movement float64
+	// Counters is the extra power each unit of this rank gets when the enemy
+	// fields at least one unit of the given rank
+	Counters map[UnitRank]int
+}
+
+type Ruleset struct {
+	Ranks map[UnitRank]RankRules
+	// Combat is the name of the CombatResolver used to fight wars
+	Combat  string
+	Victory VictoryRules
+	Economy EconomyRules
+	Turns   TurnRules
+	// FogOfWar only shows players the moves near their own units
+	FogOfWar bool
+}
+
+// Duration reads and writes durations as strings like "30m" in JSON
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+func DefaultRuleset() Ruleset {
+	return Ruleset{
+		Ranks: map[UnitRank]RankRules{
+			RankInfantry: {
+				Power:    1,
+				Cost:     1,
+				Movement: 1,
+				Counters: map[UnitRank]int{},
+			},
+			RankCavalry: {
+				Power:    5,
+				Cost:     5,
+				Movement: 0.5,
+				Counters: map[UnitRank]int{RankArtillery: 5},
+			},
+			RankArtillery: {
+				Power:    10,
+				Cost:     10,
+				Movement: 1.5,
+				Counters: map[UnitRank]int{},
+			},
+		},
+		Combat: CombatPower,
+		Victory: VictoryRules{
+			Regions:      4,
+			Elimination:  true,
+			TimeLimit:    Duration{30 * time.Minute},
+			RegionPoints: 10,
+		},
+		Economy: EconomyRules{
+			StartingFunds: 20,
+			TickInterval:  Duration{30 * time.Second},
+			Income: map[Location]int{
+				"americas":   3,
+				"europe":     3,
+				"africa":     2,
+				"asia":       3,
+				"australia":  2,
+				"antarctica": 1,
+			},
+		},
+		Turns: TurnRules{
+			Duration: Duration{time.Minute},
+		},
+		FogOfWar: false,
+	}
+}
+
+func LoadRuleset(path string) (Ruleset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Ruleset{}, fmt.Errorf("could not read ruleset: %v", err)
+	}
+	var rules Ruleset
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return Ruleset{}, fmt.Errorf("could not parse ruleset: %v", err)
+	}
+	err = rules.Validate()
+	if err != nil {
+		return Ruleset{}, err
+	}
+	return rules, nil
+}
+
+func (r Ruleset) Validate() error {
+	if len(r.Ranks) == 0 {
+		return errors.New("ruleset must define at least one rank")
+	}
+	if _, err := GetCombatResolver(r.Combat); err != nil {
+		return err
+	}
+	if r.Victory.Regions < 0 || r.Victory.TimeLimit.Duration < 0 {
+		return errors.New("victory conditions can not be negative")
+	}
+	if err := r.validateEconomy(); err != nil {
+		return err
+	}
+	if r.Turns.Duration.Duration != 0 && r.Turns.Duration.Duration < 5*time.Second {
+		return errors.New("turns must last at least 5 seconds")
+	}
+	for name, rank := range r.Ranks {
+		if rank.Power < 0 || rank.Cost < 0 {
+			return fmt.Errorf("rank %s can not have a negative power or cost", name)
+		}
+		if rank.Movement <= 0 {
+			return fmt.Errorf("rank %s must have a positive movement", name)
+		}
+		for counter := range rank.Counters {
+			if _, ok := r.Ranks[counter]; !ok {
+				return fmt.Errorf("rank %s counters unknown rank %s", name, counter)
+			}
+		}
+	}
+	return nil
+}
+
+func (r Ruleset) RankNames() []UnitRank {
+	names := []UnitRank{}
+	for name := range r.Ranks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// PowerLevel is the power units fight with against the given enemies
+func (r Ruleset) PowerLevel(units, enemies []Unit) int {
+	return unitsToPowerLevel(units, enemies, r)
+}
+
+// SetRuleset changes the rules, the funds the player already has are kept
+func (gs *GameState) SetRuleset(r Ruleset) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.rules = r
+}
+
+// JoinWith sets the rules of the game the player just joined, they start with
+// its starting funds
+func (gs *GameState) JoinWith(r Ruleset) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.rules = r
+	gs.Funds = r.Economy.StartingFunds
+}
+
+func (gs *GameState) GetRuleset() Ruleset {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.rules
+}
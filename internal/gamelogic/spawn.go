@@ -6,6 +6,9 @@ import (
 )
 
 func (gs *GameState) CommandSpawn(words []string) error {
+	if gs.isClosed() {
+		return errors.New("the game is over, you can not spawn units")
+	}
 	if len(words) < 3 {
 		return errors.New("usage: spawn <location> <rank>")
 	}
@@ -17,18 +20,23 @@ func (gs *GameState) CommandSpawn(words []string) error {
 	}
 
 	rank := words[2]
-	units := getAllRanks()
-	if _, ok := units[UnitRank(rank)]; !ok {
-		return fmt.Errorf("error: %s is not a valid unit", rank)
+	rules := gs.GetRuleset()
+	rankRules, ok := rules.Ranks[UnitRank(rank)]
+	if !ok {
+		return fmt.Errorf("error: %s is not a valid unit, choose one of %v", rank, rules.RankNames())
+	}
+	err := gs.spend(rankRules.Cost)
+	if err != nil {
+		return err
 	}
 
-	id := len(gs.getUnitsSnap()) + 1
+	id := gs.nextUnitID()
 	gs.addUnit(Unit{
 		ID:       id,
 		Rank:     UnitRank(rank),
 		Location: Location(locationName),
 	})
 
-	fmt.Printf("Spawned a(n) %s in %s with id %v\n", rank, locationName, id)
+	gs.printf("Spawned a(n) %s in %s with id %v for %v gold, %v left\n", rank, locationName, id, rankRules.Cost, gs.GetFunds())
 	return nil
 }
@@ -0,0 +1,89 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+)
+
+type PlayerStats struct {
+	Username    string
+	Wins        int
+	Losses      int
+	Draws       int
+	UnitsKilled int
+	UnitsLost   int
+	// RegionsHeld is the most regions the player ever controlled at once
+	RegionsHeld int
+}
+
+type StatsRequest struct {
+	Username string
+}
+
+type StatsResponse struct {
+	Stats PlayerStats
+	Rank  int
+	Found bool
+}
+
+// Points ranks players on the leaderboard, a win is worth three draws
+func (s PlayerStats) Points() int {
+	return s.Wins*3 + s.Draws
+}
+
+// Participants are all the players who fought in a war
+func (result WarResult) Participants() []string {
+	return append([]string{result.Attacker, result.Defender}, result.AttackerAllies...)
+}
+
+// RecordWar adds the result of a war to the stats of one of the players that
+// fought it. Allies share the wins and kills of their side, but only lose the
+// units that were theirs.
+func (s *PlayerStats) RecordWar(result WarResult) {
+	attacking := s.Username != result.Defender
+	killed := result.DefenderLosses
+	if !attacking {
+		killed = result.AttackerLosses
+	}
+	s.UnitsKilled += killed
+	s.UnitsLost += len(result.Casualties[s.Username])
+	switch {
+	case result.Draw:
+		s.Draws++
+	case (result.Winner == result.Attacker) == attacking:
+		s.Wins++
+	default:
+		s.Losses++
+	}
+}
+
+func SortLeaderboard(stats []PlayerStats) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Points() != stats[j].Points() {
+			return stats[i].Points() > stats[j].Points()
+		}
+		if stats[i].UnitsKilled != stats[j].UnitsKilled {
+			return stats[i].UnitsKilled > stats[j].UnitsKilled
+		}
+		return stats[i].Username < stats[j].Username
+	})
+}
+
+func PrintLeaderboard(stats []PlayerStats) {
+	if len(stats) == 0 {
+		fmt.Println("Nobody has fought a war yet.")
+		return
+	}
+	fmt.Println("Leaderboard:")
+	for i, s := range stats {
+		fmt.Printf("%v. %s: %v points, %v won, %v lost, %v drawn\n", i+1, s.Username, s.Points(), s.Wins, s.Losses, s.Draws)
+	}
+}
+
+func PrintStats(resp StatsResponse) {
+	s := resp.Stats
+	fmt.Printf("Stats for %s, ranked #%v:\n", s.Username, resp.Rank)
+	fmt.Printf("* wars: %v won, %v lost, %v drawn\n", s.Wins, s.Losses, s.Draws)
+	fmt.Printf("* units: %v killed, %v lost\n", s.UnitsKilled, s.UnitsLost)
+	fmt.Printf("* most regions held: %v\n", s.RegionsHeld)
+}
@@ -0,0 +1,36 @@
+package gamelogic
+
+import "testing"
+
+func TestRecordWar(t *testing.T) {
+	result := WarResult{
+		Attacker:       "alice",
+		AttackerAllies: []string{"carol"},
+		Defender:       "bob",
+		Winner:         "bob",
+		Loser:          "alice",
+		AttackerLosses: 3,
+		DefenderLosses: 1,
+		Casualties: map[string][]int{
+			"alice": {1},
+			"carol": {4, 5},
+			"bob":   {2},
+		},
+	}
+	want := map[string]PlayerStats{
+		"alice": {Username: "alice", Losses: 1, UnitsKilled: 1, UnitsLost: 1},
+		"carol": {Username: "carol", Losses: 1, UnitsKilled: 1, UnitsLost: 2},
+		"bob":   {Username: "bob", Wins: 1, UnitsKilled: 3, UnitsLost: 1},
+	}
+	participants := result.Participants()
+	if len(participants) != len(want) {
+		t.Fatalf("got participants %v, want %v of them", participants, len(want))
+	}
+	for _, username := range participants {
+		got := PlayerStats{Username: username}
+		got.RecordWar(result)
+		if got != want[username] {
+			t.Errorf("got %+v, want %+v", got, want[username])
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package gamelogic
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+type TurnRules struct {
+	// Duration is how long players have to give their orders in a turn-based
+	// game, 0 disables turn-based games
+	Duration Duration
+}
+
+func (gs *GameState) SetTurnBased(turnBased bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.TurnBased = turnBased
+}
+
+func (gs *GameState) IsTurnBased() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.TurnBased
+}
+
+func (gs *GameState) GetTurn() (int, time.Time) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.Turn, gs.Deadline
+}
+
+func (gs *GameState) setTurn(turn int, deadline time.Time) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.Turn = turn
+	gs.Deadline = deadline
+}
+
+// QueueOrder keeps a spawn or move command until the end of the turn, when
+// everyone's orders are revealed at the same time
+func (gs *GameState) QueueOrder(words []string) error {
+	if gs.isClosed() {
+		return errors.New("the game is over, you can not give orders")
+	}
+	if gs.IsPaused() {
+		return errors.New("the game is paused, you can not give orders")
+	}
+	if len(words) == 0 || (words[0] != "spawn" && words[0] != "move") {
+		return errors.New("only spawn and move orders can be queued")
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.orders = append(gs.orders, words)
+	gs.printf("Order queued for the end of turn %v: %s\n", gs.Turn, strings.Join(words, " "))
+	return nil
+}
+
+func (gs *GameState) HandleTurnStart(ts routing.TurnStart) {
+	defer gs.println("------------------------")
+	gs.println()
+	gs.printf("==== Turn %v ====\n", ts.Turn)
+	gs.printf("Give your orders before %s (%v left).\n", ts.Deadline.Format(time.Kitchen), time.Until(ts.Deadline).Round(time.Second))
+	gs.SetTurnBased(true)
+	gs.setTurn(ts.Turn, ts.Deadline)
+}
+
+// HandleTurnEnd returns the queued orders, spawns first so the new units can
+// be moved in the same turn
+func (gs *GameState) HandleTurnEnd(te routing.TurnEnd) [][]string {
+	defer gs.println("------------------------")
+	gs.println()
+	gs.printf("==== End of Turn %v ====\n", te.Turn)
+
+	gs.mu.Lock()
+	orders := gs.orders
+	gs.orders = nil
+	gs.mu.Unlock()
+
+	sorted := [][]string{}
+	for _, order := range orders {
+		if order[0] == "spawn" {
+			sorted = append(sorted, order)
+		}
+	}
+	for _, order := range orders {
+		if order[0] != "spawn" {
+			sorted = append(sorted, order)
+		}
+	}
+	gs.printf("Revealing %v order(s).\n", len(sorted))
+	return sorted
+}
+
+func (gs *GameState) queuedOrders() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return len(gs.orders)
+}
@@ -0,0 +1,135 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type VictoryRules struct {
+	// Regions wins the game for the first player controlling that many
+	// regions, 0 disables it
+	Regions int
+	// Elimination wins the game for the last player with units left
+	Elimination bool
+	// TimeLimit ends the game after the given time and the highest score
+	// wins, 0 disables it
+	TimeLimit Duration
+	// RegionPoints is added to the score for every region a player controls
+	RegionPoints int
+}
+
+type Score struct {
+	Username string
+	Units    int
+	Regions  int
+	Score    int
+}
+
+type GameOver struct {
+	Game       string
+	Winner     string
+	Reason     string
+	Scoreboard []Score
+}
+
+func (w *World) Scoreboard(rules Ruleset) []Score {
+	scores := []Score{}
+	for _, p := range w.Players() {
+		regions := len(w.RegionsControlled(p.Username))
+		power := 0
+		for _, u := range p.Units {
+			power += rules.Ranks[u.Rank].Power
+		}
+		scores = append(scores, Score{
+			Username: p.Username,
+			Units:    len(p.Units),
+			Regions:  regions,
+			Score:    power + regions*rules.Victory.RegionPoints,
+		})
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores
+}
+
+// CheckVictory evaluates the victory conditions of the ruleset and reports
+// whether the game is over, a game needs at least two players to be won
+func (w *World) CheckVictory(rules Ruleset, now time.Time) (GameOver, bool) {
+	v := rules.Victory
+	scoreboard := w.Scoreboard(rules)
+	if len(scoreboard) < 2 {
+		return GameOver{}, false
+	}
+
+	if v.Regions > 0 {
+		for _, s := range scoreboard {
+			if s.Regions >= v.Regions {
+				return GameOver{
+					Winner:     s.Username,
+					Reason:     fmt.Sprintf("%s controls %v regions", s.Username, s.Regions),
+					Scoreboard: scoreboard,
+				}, true
+			}
+		}
+	}
+
+	if v.Elimination {
+		fielded := 0
+		survivors := []string{}
+		for _, s := range scoreboard {
+			if !w.hasFielded(s.Username) {
+				continue
+			}
+			fielded++
+			if !w.isEliminated(s.Username) {
+				survivors = append(survivors, s.Username)
+			}
+		}
+		// a single player can't be the last one standing
+		if fielded > 1 && len(survivors) == 1 {
+			return GameOver{
+				Winner:     survivors[0],
+				Reason:     fmt.Sprintf("%s eliminated all opponents", survivors[0]),
+				Scoreboard: scoreboard,
+			}, true
+		}
+	}
+
+	started := w.Started()
+	if v.TimeLimit.Duration > 0 && !started.IsZero() && now.Sub(started) >= v.TimeLimit.Duration {
+		over := GameOver{
+			Reason:     "the time limit was reached",
+			Scoreboard: scoreboard,
+		}
+		if len(scoreboard) > 0 {
+			over.Winner = scoreboard[0].Username
+		}
+		return over, true
+	}
+
+	return GameOver{}, false
+}
+
+func FormatScoreboard(scoreboard []Score) string {
+	lines := []string{}
+	for i, s := range scoreboard {
+		lines = append(lines, fmt.Sprintf("%v. %s: %v points (%v units, %v regions)", i+1, s.Username, s.Score, s.Units, s.Regions))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (gs *GameState) HandleGameOver(over GameOver) {
+	defer gs.println("------------------------")
+	gs.println()
+	gs.println("==== Game Over ====")
+	gs.printf("The game is over, %s.\n", over.Reason)
+	if over.Winner == gs.GetUsername() {
+		gs.println("You have won the game!")
+	} else if over.Winner != "" {
+		gs.printf("%s has won the game!\n", over.Winner)
+	}
+	gs.println(FormatScoreboard(over.Scoreboard))
+	gs.println("You can no longer spawn or move units, type quit to leave.")
+	gs.closeGame()
+}
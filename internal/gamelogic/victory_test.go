@@ -0,0 +1,80 @@
+package gamelogic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckVictory(t *testing.T) {
+	rules := DefaultRuleset()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := rules.Victory.TimeLimit.Duration
+	army := func(username string, locations ...Location) Player {
+		p := Player{Username: username, Units: map[int]Unit{}}
+		for i, loc := range locations {
+			p.Units[i+1] = Unit{ID: i + 1, Rank: RankInfantry, Location: loc}
+		}
+		return p
+	}
+	tests := []struct {
+		name    string
+		players []Player
+		started bool
+		now     time.Time
+		over    bool
+		winner  string
+	}{
+		{
+			name:    "a lone player holding every region",
+			players: []Player{army("alice", "americas", "europe", "africa", "asia", "antarctica", "australia")},
+			started: true,
+			now:     start,
+		},
+		{
+			name:    "regions",
+			players: []Player{army("alice", "americas", "europe", "africa", "asia"), army("bob", "australia")},
+			started: true,
+			now:     start,
+			over:    true,
+			winner:  "alice",
+		},
+		{
+			name:    "time limit not reached",
+			players: []Player{army("alice", "europe"), army("bob", "asia")},
+			started: true,
+			now:     start.Add(limit - time.Second),
+		},
+		{
+			name:    "time limit reached",
+			players: []Player{army("alice", "europe", "africa"), army("bob", "asia")},
+			started: true,
+			now:     start.Add(limit),
+			over:    true,
+			winner:  "alice",
+		},
+		{
+			name:    "clock not started",
+			players: []Player{army("alice", "europe"), army("bob", "asia")},
+			now:     start.Add(2 * limit),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWorld()
+			if tt.started {
+				w.Start(start)
+				w.Start(start.Add(time.Hour))
+			}
+			for _, p := range tt.players {
+				w.UpdatePlayer(p)
+			}
+			over, ok := w.CheckVictory(rules, tt.now)
+			if ok != tt.over {
+				t.Fatalf("got over %v, want %v (%s)", ok, tt.over, over.Reason)
+			}
+			if over.Winner != tt.winner {
+				t.Errorf("got winner %q, want %q", over.Winner, tt.winner)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package gamelogic
 
 import (
-	"fmt"
+	"slices"
+	"sort"
+	"time"
 )
 
 type WarOutcome int
@@ -14,91 +16,259 @@ const (
 	WarOutcomeDraw
 )
 
-func (gs *GameState) HandleWar(rw RecognitionOfWar) (outcome WarOutcome, winner string, loser string) {
-	defer fmt.Println("------------------------")
-	fmt.Println()
-	fmt.Println("==== War Declared ====")
-	fmt.Printf("%s has declared war on %s!\n", rw.Attacker.Username, rw.Defender.Username)
+// WarResult is the outcome of a war as recorded by the server. In a draw
+// Winner and Loser are just the attacker and the defender.
+type WarResult struct {
+	Game     string
+	Location Location
+	Attacker string
+	// AttackerAllies are the allies whose units fought on the attacker's side
+	AttackerAllies []string
+	Defender       string
+	Winner         string
+	Loser          string
+	Draw           bool
+	AttackerLosses int
+	DefenderLosses int
+	// Casualties has the IDs of the units killed for every player, so the
+	// players who didn't resolve the war can remove theirs
+	Casualties map[string][]int
+	ResolvedBy string
+	FoughtAt   time.Time
+}
+
+type casualty struct {
+	username string
+	id       int
+}
+
+// WarLocations returns the locations where the player has to declare war on
+// the enemy. When allies share a location with the enemy only the one with the
+// smallest username declares, the others fight on their side.
+func (gs *GameState) WarLocations(enemy Player) []Location {
+	username := gs.GetUsername()
+	allies := gs.GetAllySnaps()
+	locations := []Location{}
+	for _, loc := range GetOverlappingLocations(gs.GetPlayerSnap(), enemy) {
+		declares := true
+		for _, ally := range allies {
+			if ally.Username < username && hasUnitsIn(ally, loc) {
+				declares = false
+				break
+			}
+		}
+		if declares {
+			locations = append(locations, loc)
+		}
+	}
+	return locations
+}
+
+func hasUnitsIn(p Player, loc Location) bool {
+	for _, unit := range p.Units {
+		if unit.Location == loc && !unit.InTransit() {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleWarResult removes the units of the player that were killed in a war
+// resolved by someone else
+func (gs *GameState) HandleWarResult(result WarResult) bool {
+	gs.removeAllyCasualties(result.Casualties)
+	ids := result.Casualties[gs.GetUsername()]
+	if len(ids) == 0 || result.ResolvedBy == gs.GetUsername() {
+		return false
+	}
+	gs.removeCasualties(result.Casualties, result.Location)
+	return true
+}
+
+func (gs *GameState) removeCasualties(casualties map[string][]int, loc Location) {
+	ids := casualties[gs.GetUsername()]
+	if len(ids) == 0 {
+		return
+	}
+	units := []Unit{}
+	for _, id := range ids {
+		units = append(units, Unit{ID: id})
+	}
+	gs.removeUnits(units)
+	gs.printf("%v of your units in %s have been killed.\n", len(ids), loc)
+}
+
+// removeAllyCasualties keeps the snapshots of allies up to date after a war,
+// so they don't fight with units they lost
+func (gs *GameState) removeAllyCasualties(casualties map[string][]int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for username, ids := range casualties {
+		ally, ok := gs.allies[username]
+		if !ok {
+			continue
+		}
+		units := map[int]Unit{}
+		for id, unit := range ally.Units {
+			units[id] = unit
+		}
+		for _, id := range ids {
+			delete(units, id)
+		}
+		gs.allies[username] = Player{Username: ally.Username, Units: units}
+	}
+}
+
+func (gs *GameState) HandleWar(rw RecognitionOfWar) (outcome WarOutcome, result WarResult) {
+	defer gs.println("------------------------")
+	gs.println()
+	gs.println("==== War Declared ====")
+	gs.printf("%s has declared war on %s!\n", rw.Attacker.Username, rw.Defender.Username)
 
 	player := gs.GetPlayerSnap()
 
 	if player.Username == rw.Defender.Username {
-		fmt.Printf("%s, you published the war.\n", player.Username)
-		return WarOutcomeNotInvolved, "", ""
+		gs.printf("%s, you published the war.\n", player.Username)
+		return WarOutcomeNotInvolved, WarResult{}
 	}
 
 	if player.Username != rw.Attacker.Username {
-		fmt.Printf("%s, you are not involved in this war.\n", player.Username)
-		return WarOutcomeNotInvolved, "", ""
+		gs.printf("%s, you are not involved in this war.\n", player.Username)
+		return WarOutcomeNotInvolved, WarResult{}
 	}
 
-	overlappingLocation := getOverlappingLocation(rw.Attacker, rw.Defender)
-	if overlappingLocation == "" {
-		fmt.Printf("Error! No units are in the same location. No war will be fought.\n")
-		return WarOutcomeNoUnits, "", ""
+	overlappingLocations := GetOverlappingLocations(rw.Attacker, rw.Defender)
+	if len(overlappingLocations) == 0 {
+		gs.printf("Error! No units are in the same location. No war will be fought.\n")
+		return WarOutcomeNoUnits, WarResult{}
+	}
+	// wars from older clients don't say where they are fought
+	overlappingLocation := overlappingLocations[0]
+	if rw.Location != "" {
+		if !slices.Contains(overlappingLocations, rw.Location) {
+			gs.printf("Error! No units are in %s. No war will be fought.\n", rw.Location)
+			return WarOutcomeNoUnits, WarResult{}
+		}
+		overlappingLocation = rw.Location
 	}
+	gs.printf("The battle takes place in %s.\n", overlappingLocation)
 
-	attackerUnits := []Unit{}
-	defenderUnits := []Unit{}
-	for _, unit := range rw.Attacker.Units {
-		if unit.Location == overlappingLocation {
-			attackerUnits = append(attackerUnits, unit)
+	// units of different players can share IDs, so they get a new one for the
+	// battle and are mapped back to their owner afterwards
+	owners := map[int]casualty{}
+	// the IDs are handed out in order, so everyone resolving the same war with
+	// the same seed gets the same battle
+	takePart := func(side []Unit, p Player) []Unit {
+		ids := []int{}
+		for id := range p.Units {
+			ids = append(ids, id)
 		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			unit := p.Units[id]
+			if unit.Location != overlappingLocation || unit.InTransit() {
+				continue
+			}
+			battleID := len(owners) + 1
+			owners[battleID] = casualty{username: p.Username, id: unit.ID}
+			unit.ID = battleID
+			side = append(side, unit)
+		}
+		return side
 	}
-	for _, unit := range rw.Defender.Units {
-		if unit.Location == overlappingLocation {
-			defenderUnits = append(defenderUnits, unit)
+	attackerUnits := takePart([]Unit{}, rw.Attacker)
+	allies := []string{}
+	for _, ally := range rw.AttackerAllies {
+		alliedUnits := takePart([]Unit{}, ally)
+		if len(alliedUnits) > 0 {
+			gs.printf("%s joins the battle on %s's side!\n", ally.Username, rw.Attacker.Username)
+			allies = append(allies, ally.Username)
 		}
+		attackerUnits = append(attackerUnits, alliedUnits...)
 	}
+	defenderUnits := takePart([]Unit{}, rw.Defender)
 
-	fmt.Printf("%s's units:\n", rw.Attacker.Username)
+	resolver, err := GetCombatResolver(rw.Combat)
+	if err != nil {
+		gs.println(err)
+		return WarOutcomeNoUnits, WarResult{}
+	}
+
+	gs.printf("%s's units:\n", rw.Attacker.Username)
 	for _, unit := range attackerUnits {
-		fmt.Printf("  * %v\n", unit.Rank)
+		gs.printf("  * %v\n", unit.Rank)
 	}
-	fmt.Printf("%s's units:\n", rw.Defender.Username)
+	gs.printf("%s's units:\n", rw.Defender.Username)
 	for _, unit := range defenderUnits {
-		fmt.Printf("  * %v\n", unit.Rank)
-	}
-	attackerPower := unitsToPowerLevel(attackerUnits)
-	defenderPower := unitsToPowerLevel(defenderUnits)
-	fmt.Printf("Attacker has a power level of %v\n", attackerPower)
-	fmt.Printf("Defender has a power level of %v\n", defenderPower)
-	if attackerPower > defenderPower {
-		fmt.Printf("%s has won the war!\n", rw.Attacker.Username)
+		gs.printf("  * %v\n", unit.Rank)
+	}
+	battle := resolver.Resolve(attackerUnits, defenderUnits, gs.GetRuleset(), rw.Seed)
+	gs.printf("Attacker has a power level of %v\n", battle.AttackerPower)
+	gs.printf("Defender has a power level of %v\n", battle.DefenderPower)
+	for i, round := range battle.Rounds {
+		gs.printf("Round %v: attacker rolled %v, defender rolled %v\n", i+1, round.AttackerRolls, round.DefenderRolls)
+	}
+
+	casualties := map[string][]int{}
+	for _, unit := range append(battle.AttackerLosses, battle.DefenderLosses...) {
+		c := owners[unit.ID]
+		casualties[c.username] = append(casualties[c.username], c.id)
+	}
+	gs.removeCasualties(casualties, overlappingLocation)
+	gs.removeAllyCasualties(casualties)
+
+	result = WarResult{
+		Game:           gs.GetGame(),
+		Location:       overlappingLocation,
+		Attacker:       rw.Attacker.Username,
+		AttackerAllies: allies,
+		Defender:       rw.Defender.Username,
+		AttackerLosses: len(battle.AttackerLosses),
+		DefenderLosses: len(battle.DefenderLosses),
+		Casualties:     casualties,
+		ResolvedBy:     player.Username,
+		FoughtAt:       time.Now(),
+	}
+	switch battle.Outcome {
+	case BattleAttackerWon:
+		gs.printf("%s has won the war!\n", rw.Attacker.Username)
+		result.Winner, result.Loser = rw.Attacker.Username, rw.Defender.Username
 		if player.Username == rw.Defender.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Attacker.Username, rw.Defender.Username
+			gs.println("You have lost the war!")
+			return WarOutcomeOpponentWon, result
 		}
-		return WarOutcomeYouWon, rw.Attacker.Username, rw.Defender.Username
-	} else if defenderPower > attackerPower {
-		fmt.Printf("%s has won the war!\n", rw.Defender.Username)
+		return WarOutcomeYouWon, result
+	case BattleDefenderWon:
+		gs.printf("%s has won the war!\n", rw.Defender.Username)
+		result.Winner, result.Loser = rw.Defender.Username, rw.Attacker.Username
 		if player.Username == rw.Attacker.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Defender.Username, rw.Attacker.Username
+			gs.println("You have lost the war!")
+			return WarOutcomeOpponentWon, result
 		}
-		return WarOutcomeYouWon, rw.Defender.Username, rw.Attacker.Username
+		return WarOutcomeYouWon, result
 	}
-	fmt.Println("The war ended in a draw!")
-	fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-	gs.removeUnitsInLocation(overlappingLocation)
-	return WarOutcomeDraw, rw.Attacker.Username, rw.Defender.Username
+	gs.println("The war ended in a draw!")
+	result.Draw = true
+	result.Winner, result.Loser = rw.Attacker.Username, rw.Defender.Username
+	return WarOutcomeDraw, result
 }
 
-func unitsToPowerLevel(units []Unit) int {
+func unitsToPowerLevel(units []Unit, enemies []Unit, rules Ruleset) int {
+	enemyRanks := map[UnitRank]bool{}
+	for _, enemy := range enemies {
+		enemyRanks[enemy.Rank] = true
+	}
 	power := 0
 	for _, unit := range units {
-		if unit.Rank == RankArtillery {
-			power += 10
-		}
-		if unit.Rank == RankCavalry {
-			power += 5
+		rank, ok := rules.Ranks[unit.Rank]
+		if !ok {
+			continue
 		}
-		if unit.Rank == RankInfantry {
-			power += 1
+		power += rank.Power
+		for counter, bonus := range rank.Counters {
+			if enemyRanks[counter] {
+				power += bonus
+			}
 		}
 	}
 	return power
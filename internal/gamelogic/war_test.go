@@ -0,0 +1,118 @@
+package gamelogic
+
+import (
+	"io"
+	"testing"
+)
+
+func TestHandleWarIsDeterministic(t *testing.T) {
+	attacker := Player{Username: "alice", Units: map[int]Unit{}}
+	defender := Player{Username: "bob", Units: map[int]Unit{}}
+	ranks := []UnitRank{RankInfantry, RankCavalry, RankArtillery}
+	for id := 1; id <= 12; id++ {
+		attacker.Units[id] = Unit{ID: id, Rank: ranks[id%3], Location: "europe"}
+		defender.Units[id] = Unit{ID: id, Rank: ranks[(id+1)%3], Location: "europe"}
+	}
+	rw := RecognitionOfWar{
+		Attacker: attacker,
+		Defender: defender,
+		Location: "europe",
+		Combat:   CombatDice,
+		Seed:     42,
+	}
+	resolve := func() WarResult {
+		gs := NewGameState("alice")
+		gs.SetOutput(io.Discard)
+		for _, unit := range attacker.Units {
+			gs.addUnit(unit)
+		}
+		_, result := gs.HandleWar(rw)
+		return result
+	}
+	want := resolve()
+	for i := 0; i < 20; i++ {
+		got := resolve()
+		if got.Winner != want.Winner || got.AttackerLosses != want.AttackerLosses || got.DefenderLosses != want.DefenderLosses {
+			t.Fatalf("war %v resolved differently: %+v, want %+v", i, got, want)
+		}
+		for username, ids := range want.Casualties {
+			if len(got.Casualties[username]) != len(ids) {
+				t.Fatalf("war %v killed %v of %s's units, want %v", i, got.Casualties[username], username, ids)
+			}
+			for j := range ids {
+				if got.Casualties[username][j] != ids[j] {
+					t.Fatalf("war %v killed %v of %s's units, want %v", i, got.Casualties[username], username, ids)
+				}
+			}
+		}
+	}
+}
+
+func TestWarLocations(t *testing.T) {
+	enemy := Player{Username: "mallory", Units: map[int]Unit{
+		1: {ID: 1, Rank: RankInfantry, Location: "europe"},
+		2: {ID: 2, Rank: RankInfantry, Location: "asia"},
+	}}
+	tests := []struct {
+		name     string
+		username string
+		ally     Player
+		want     []Location
+	}{
+		{
+			name:     "no ally around",
+			username: "bob",
+			ally:     Player{Username: "alice", Units: map[int]Unit{1: {ID: 1, Location: "africa"}}},
+			want:     []Location{"asia", "europe"},
+		},
+		{
+			name:     "the ally declares",
+			username: "bob",
+			ally:     Player{Username: "alice", Units: map[int]Unit{1: {ID: 1, Location: "europe"}}},
+			want:     []Location{"asia"},
+		},
+		{
+			name:     "we declare",
+			username: "alice",
+			ally:     Player{Username: "bob", Units: map[int]Unit{1: {ID: 1, Location: "europe"}}},
+			want:     []Location{"asia", "europe"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := NewGameState(tt.username)
+			gs.SetOutput(io.Discard)
+			gs.addUnit(Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+			gs.addUnit(Unit{ID: 2, Rank: RankInfantry, Location: "asia"})
+			gs.allies[tt.ally.Username] = tt.ally
+			got := gs.WarLocations(enemy)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAllySnapshotsStayFresh(t *testing.T) {
+	gs := NewGameState("bob")
+	gs.SetOutput(io.Discard)
+	gs.allies["alice"] = Player{Username: "alice", Units: map[int]Unit{
+		1: {ID: 1, Location: "europe"},
+		2: {ID: 2, Location: "europe"},
+	}}
+
+	gs.HandleWarResult(WarResult{Casualties: map[string][]int{"alice": {1}}, ResolvedBy: "alice"})
+	if units := gs.GetAllySnaps()[0].Units; len(units) != 1 {
+		t.Fatalf("ally has %v units after the war, want 1", len(units))
+	}
+
+	gs.HandleDiplomacy(DiplomacyMessage{Type: DiplomacyStatus, From: "alice", To: "bob", Player: Player{Username: "alice", Units: map[int]Unit{}}})
+	if units := gs.GetAllySnaps()[0].Units; len(units) != 0 {
+		t.Fatalf("ally has %v units after their status, want 0", len(units))
+	}
+}
@@ -0,0 +1,155 @@
+package gamelogic
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// PlayerStatus is published by a client whenever its units change, so the
+// server can keep track of the whole game
+type PlayerStatus struct {
+	Game   string
+	Player Player
+	Funds  int
+}
+
+// World is the server's view of a game, built from the statuses published by
+// every player
+type World struct {
+	players map[string]Player
+	// fielded remembers who has ever had units, a player that has none left
+	// after that has been eliminated
+	fielded map[string]bool
+	// started is when the first player joined, the zero time until then
+	started time.Time
+	mu      *sync.RWMutex
+}
+
+func NewWorld() *World {
+	return &World{
+		players: map[string]Player{},
+		fielded: map[string]bool{},
+		mu:      &sync.RWMutex{},
+	}
+}
+
+func (w *World) UpdatePlayer(p Player) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	units := map[int]Unit{}
+	for k, v := range p.Units {
+		units[k] = v
+	}
+	w.players[p.Username] = Player{Username: p.Username, Units: units}
+	if len(units) > 0 {
+		w.fielded[p.Username] = true
+	}
+}
+
+// Start starts the game clock, only the first call counts
+func (w *World) Start(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started.IsZero() {
+		w.started = now
+	}
+}
+
+func (w *World) Started() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.started
+}
+
+func (w *World) GetPlayer(username string) (Player, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	p, ok := w.players[username]
+	return p, ok
+}
+
+func (w *World) Players() []Player {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	players := []Player{}
+	for _, p := range w.players {
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Username < players[j].Username })
+	return players
+}
+
+// RegionsControlled returns the locations where the player is the only one
+// with units
+func (w *World) RegionsControlled(username string) []Location {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	occupants := map[Location]map[string]bool{}
+	for _, p := range w.players {
+		for _, u := range p.Units {
+			if u.InTransit() {
+				continue
+			}
+			if occupants[u.Location] == nil {
+				occupants[u.Location] = map[string]bool{}
+			}
+			occupants[u.Location][p.Username] = true
+		}
+	}
+	regions := []Location{}
+	for loc, who := range occupants {
+		if len(who) == 1 && who[username] {
+			regions = append(regions, loc)
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i] < regions[j] })
+	return regions
+}
+
+func (w *World) isEliminated(username string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.fielded[username] && len(w.players[username].Units) == 0
+}
+
+func (w *World) hasFielded(username string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.fielded[username]
+}
+
+// RegionView is a region as spectators see it
+type RegionView struct {
+	Location Location
+	// Units maps every player in the region to the ranks of their units
+	Units map[string][]UnitRank
+}
+
+// Regions returns every region sorted by name, empty ones included
+func (w *World) Regions() []RegionView {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	byLocation := map[Location]RegionView{}
+	for loc := range getAllLocations() {
+		byLocation[loc] = RegionView{Location: loc, Units: map[string][]UnitRank{}}
+	}
+	for _, p := range w.players {
+		for _, unit := range p.Units {
+			region, ok := byLocation[unit.Location]
+			if !ok {
+				continue
+			}
+			region.Units[p.Username] = append(region.Units[p.Username], unit.Rank)
+		}
+	}
+	regions := []RegionView{}
+	for _, region := range byLocation {
+		for _, ranks := range region.Units {
+			sort.Slice(ranks, func(i, j int) bool { return ranks[i] < ranks[j] })
+		}
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Location < regions[j].Location })
+	return regions
+}
@@ -0,0 +1,109 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Route struct {
+	From       Location
+	To         Location
+	TravelTime time.Duration
+}
+
+// routes are bidirectional, so each pair only needs to be listed once
+var routes = []Route{
+	{From: "americas", To: "europe", TravelTime: 20 * time.Second},
+	{From: "americas", To: "africa", TravelTime: 25 * time.Second},
+	{From: "americas", To: "asia", TravelTime: 30 * time.Second},
+	{From: "americas", To: "antarctica", TravelTime: 25 * time.Second},
+	{From: "europe", To: "africa", TravelTime: 10 * time.Second},
+	{From: "europe", To: "asia", TravelTime: 15 * time.Second},
+	{From: "africa", To: "asia", TravelTime: 20 * time.Second},
+	{From: "africa", To: "antarctica", TravelTime: 25 * time.Second},
+	{From: "asia", To: "australia", TravelTime: 15 * time.Second},
+	{From: "australia", To: "antarctica", TravelTime: 20 * time.Second},
+}
+
+func getAdjacency() map[Location]map[Location]time.Duration {
+	adjacency := map[Location]map[Location]time.Duration{}
+	for loc := range getAllLocations() {
+		adjacency[loc] = map[Location]time.Duration{}
+	}
+	for _, r := range routes {
+		adjacency[r.From][r.To] = r.TravelTime
+		adjacency[r.To][r.From] = r.TravelTime
+	}
+	return adjacency
+}
+
+func AllLocations() []Location {
+	locations := []Location{}
+	for loc := range getAllLocations() {
+		locations = append(locations, loc)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i] < locations[j] })
+	return locations
+}
+
+func GetNeighbors(loc Location) []Location {
+	neighbors := []Location{}
+	for n := range getAdjacency()[loc] {
+		neighbors = append(neighbors, n)
+	}
+	sort.Slice(neighbors, func(i, j int) bool { return neighbors[i] < neighbors[j] })
+	return neighbors
+}
+
+// FindPath returns the quickest path between two locations, including both
+// ends, and the total time it takes to travel it.
+func FindPath(from, to Location) ([]Location, time.Duration, error) {
+	adjacency := getAdjacency()
+	if _, ok := adjacency[from]; !ok {
+		return nil, 0, fmt.Errorf("error: %s is not a valid location", from)
+	}
+	if _, ok := adjacency[to]; !ok {
+		return nil, 0, fmt.Errorf("error: %s is not a valid location", to)
+	}
+
+	dist := map[Location]time.Duration{from: 0}
+	prev := map[Location]Location{}
+	visited := map[Location]bool{}
+	for {
+		current := Location("")
+		for loc, d := range dist {
+			if visited[loc] {
+				continue
+			}
+			if current == "" || d < dist[current] || (d == dist[current] && loc < current) {
+				current = loc
+			}
+		}
+		if current == "" {
+			break
+		}
+		if current == to {
+			break
+		}
+		visited[current] = true
+		for next, cost := range adjacency[current] {
+			d, seen := dist[next]
+			if !seen || dist[current]+cost < d {
+				dist[next] = dist[current] + cost
+				prev[next] = current
+			}
+		}
+	}
+
+	total, ok := dist[to]
+	if !ok {
+		return nil, 0, fmt.Errorf("error: there is no route from %s to %s", from, to)
+	}
+	path := []Location{to}
+	for loc := to; loc != from; {
+		loc = prev[loc]
+		path = append([]Location{loc}, path...)
+	}
+	return path, total, nil
+}
@@ -0,0 +1,40 @@
+package gamelogic
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    Location
+		to      Location
+		path    []Location
+		total   time.Duration
+		wantErr bool
+	}{
+		{"same place", "europe", "europe", []Location{"europe"}, 0, false},
+		{"neighbors", "europe", "africa", []Location{"europe", "africa"}, 10 * time.Second, false},
+		{"no direct route", "antarctica", "asia", []Location{"antarctica", "australia", "asia"}, 35 * time.Second, false},
+		{"direct is quicker", "americas", "asia", []Location{"americas", "asia"}, 30 * time.Second, false},
+		{"across the map", "europe", "australia", []Location{"europe", "asia", "australia"}, 30 * time.Second, false},
+		{"unknown start", "atlantis", "europe", nil, 0, true},
+		{"unknown end", "europe", "atlantis", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, total, err := FindPath(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(path, tt.path) {
+				t.Errorf("got path %v, want %v", path, tt.path)
+			}
+			if total != tt.total {
+				t.Errorf("got %v, want %v", total, tt.total)
+			}
+		})
+	}
+}
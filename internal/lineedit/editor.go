@@ -0,0 +1,269 @@
+package lineedit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is how many lines are loaded back from a history file
+const maxHistory = 1000
+
+// Completer lists the words that can follow the ones already typed
+type Completer func(words []string) []string
+
+// Editor is a line being typed with the lines entered before it, the zero
+// value is ready to use
+type Editor struct {
+	line   []rune
+	cursor int
+	// history holds the submitted lines, pos is the one being shown and equals
+	// len(history) while editing a new line, kept in draft meanwhile
+	history []string
+	pos     int
+	draft   []rune
+	// historyPath is where submitted lines are saved, if set
+	historyPath string
+}
+
+// LoadHistory reads the lines saved in path by earlier sessions and saves the
+// new ones there, a missing file is fine
+func (e *Editor) LoadHistory(path string) error {
+	e.historyPath = path
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open history: %v", err)
+	}
+	defer f.Close()
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read history: %v", err)
+	}
+	e.history = append(lines[max(len(lines)-maxHistory, 0):], e.history...)
+	e.pos = len(e.history)
+	return nil
+}
+
+func (e *Editor) saveHistory(line string) {
+	if e.historyPath == "" {
+		return
+	}
+	f, err := os.OpenFile(e.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// Line is the text typed so far
+func (e *Editor) Line() string {
+	return string(e.line)
+}
+
+// Cursor is the position of the cursor in the line, in runes
+func (e *Editor) Cursor() int {
+	return e.cursor
+}
+
+func (e *Editor) Empty() bool {
+	return len(e.line) == 0
+}
+
+// Apply carries out the editing keys, it returns false for the keys it leaves
+// to the caller like enter and tab
+func (e *Editor) Apply(k Key, r rune) bool {
+	switch k {
+	case KeyRune:
+		e.Insert(r)
+	case KeyBackspace:
+		e.Backspace()
+	case KeyDelete:
+		e.Delete()
+	case KeyDeleteWord:
+		e.DeleteWord()
+	case KeyKillLine:
+		e.Clear()
+	case KeyLeft:
+		e.Left()
+	case KeyRight:
+		e.Right()
+	case KeyHome:
+		e.Home()
+	case KeyEnd:
+		e.End()
+	case KeyUp:
+		e.Prev()
+	case KeyDown:
+		e.Next()
+	default:
+		return false
+	}
+	return true
+}
+
+func (e *Editor) Insert(r rune) {
+	e.line = append(e.line[:e.cursor], append([]rune{r}, e.line[e.cursor:]...)...)
+	e.cursor++
+}
+
+func (e *Editor) Backspace() {
+	if e.cursor == 0 {
+		return
+	}
+	e.line = append(e.line[:e.cursor-1], e.line[e.cursor:]...)
+	e.cursor--
+}
+
+func (e *Editor) Delete() {
+	if e.cursor == len(e.line) {
+		return
+	}
+	e.line = append(e.line[:e.cursor], e.line[e.cursor+1:]...)
+}
+
+// DeleteWord removes the word before the cursor like ctrl-w in a shell
+func (e *Editor) DeleteWord() {
+	start := e.cursor
+	for start > 0 && e.line[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.line[start-1] != ' ' {
+		start--
+	}
+	e.line = append(e.line[:start], e.line[e.cursor:]...)
+	e.cursor = start
+}
+
+func (e *Editor) Clear() {
+	e.line = nil
+	e.cursor = 0
+}
+
+func (e *Editor) Left() {
+	if e.cursor > 0 {
+		e.cursor--
+	}
+}
+
+func (e *Editor) Right() {
+	if e.cursor < len(e.line) {
+		e.cursor++
+	}
+}
+
+func (e *Editor) Home() {
+	e.cursor = 0
+}
+
+func (e *Editor) End() {
+	e.cursor = len(e.line)
+}
+
+// Prev and Next walk through the history, the line being typed is kept
+// aside until we come back to it
+func (e *Editor) Prev() {
+	if e.pos == 0 {
+		return
+	}
+	if e.pos == len(e.history) {
+		e.draft = e.line
+	}
+	e.pos--
+	e.show([]rune(e.history[e.pos]))
+}
+
+func (e *Editor) Next() {
+	if e.pos >= len(e.history) {
+		return
+	}
+	e.pos++
+	if e.pos == len(e.history) {
+		e.show(e.draft)
+		return
+	}
+	e.show([]rune(e.history[e.pos]))
+}
+
+func (e *Editor) show(line []rune) {
+	e.line = append([]rune{}, line...)
+	e.cursor = len(e.line)
+}
+
+// Submit returns the line and starts a new one, lines worth typing again go
+// in the history
+func (e *Editor) Submit() string {
+	line := strings.TrimSpace(string(e.line))
+	if line != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+		e.history = append(e.history, line)
+		e.saveHistory(line)
+	}
+	e.pos = len(e.history)
+	e.draft = nil
+	e.Clear()
+	return line
+}
+
+// Complete finishes the word under the cursor with the candidates for its
+// position, it returns the matches when there is more than one
+func (e *Editor) Complete(candidates Completer) []string {
+	if candidates == nil {
+		return nil
+	}
+	start := e.cursor
+	for start > 0 && e.line[start-1] != ' ' {
+		start--
+	}
+	partial := string(e.line[start:e.cursor])
+	matches := []string{}
+	for _, c := range candidates(strings.Fields(string(e.line[:start]))) {
+		if strings.HasPrefix(c, partial) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	completion := matches[0]
+	for _, m := range matches[1:] {
+		completion = commonPrefix(completion, m)
+	}
+	if len(matches) == 1 {
+		completion += " "
+	}
+	for _, r := range []rune(completion)[len([]rune(partial)):] {
+		e.Insert(r)
+	}
+	if len(matches) == 1 {
+		return nil
+	}
+	return matches
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// DefaultHistoryPath is ~/.<name>_history, or nothing when there is no home
+func DefaultHistoryPath(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "."+name+"_history")
+}
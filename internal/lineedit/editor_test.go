@@ -0,0 +1,142 @@
+package lineedit
+
+import (
+	"reflect"
+	"testing"
+)
+
+// typeKeys applies the keys to the editor, strings are typed rune by rune
+func typeKeys(e *Editor, keys ...any) {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case Key:
+			e.Apply(k, 0)
+		case string:
+			for _, r := range k {
+				e.Apply(KeyRune, r)
+			}
+		}
+	}
+}
+
+func TestEditorKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []any
+		line   string
+		cursor int
+	}{
+		{"typing", []any{"move asia"}, "move asia", 9},
+		{"backspace", []any{"moves", KeyBackspace}, "move", 4},
+		{"backspace at the start", []any{"move", KeyHome, KeyBackspace}, "move", 0},
+		{"delete", []any{"xmove", KeyHome, KeyDelete}, "move", 0},
+		{"delete at the end", []any{"move", KeyDelete}, "move", 4},
+		{"insert in the middle", []any{"mve", KeyLeft, KeyLeft, "o"}, "move", 2},
+		{"left stops at the start", []any{"ab", KeyLeft, KeyLeft, KeyLeft}, "ab", 0},
+		{"right stops at the end", []any{"ab", KeyHome, KeyRight, KeyRight, KeyRight}, "ab", 2},
+		{"end", []any{"move", KeyHome, KeyEnd}, "move", 4},
+		{"delete word", []any{"move asia 1", KeyDeleteWord}, "move asia ", 10},
+		{"delete word and spaces", []any{"move asia  ", KeyDeleteWord}, "move ", 5},
+		{"delete word mid line", []any{"move asia 1", KeyLeft, KeyLeft, KeyDeleteWord}, "move  1", 5},
+		{"kill line", []any{"move asia", KeyKillLine}, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Editor
+			typeKeys(&e, tt.keys...)
+			if e.Line() != tt.line || e.Cursor() != tt.cursor {
+				t.Errorf("got %q with the cursor at %v, want %q at %v", e.Line(), e.Cursor(), tt.line, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestEditorApplyLeavesKeys(t *testing.T) {
+	var e Editor
+	for _, k := range []Key{KeyEnter, KeyTab, KeyInterrupt, KeyEOF, KeyRedraw} {
+		if e.Apply(k, 0) {
+			t.Errorf("Apply(%v) = true, want the caller to handle it", k)
+		}
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	var e Editor
+	for _, line := range []string{"spawn asia infantry", "status", "status", "  ", "move asia 1"} {
+		typeKeys(&e, line)
+		e.Submit()
+	}
+	if want := []string{"spawn asia infantry", "status", "move asia 1"}; !reflect.DeepEqual(e.history, want) {
+		t.Fatalf("got history %q, want %q", e.history, want)
+	}
+
+	typeKeys(&e, "hel")
+	steps := []struct {
+		key  Key
+		line string
+	}{
+		{KeyUp, "move asia 1"},
+		{KeyUp, "status"},
+		{KeyUp, "spawn asia infantry"},
+		{KeyUp, "spawn asia infantry"},
+		{KeyDown, "status"},
+		{KeyDown, "move asia 1"},
+		// the line being typed comes back
+		{KeyDown, "hel"},
+		{KeyDown, "hel"},
+	}
+	for i, step := range steps {
+		e.Apply(step.key, 0)
+		if e.Line() != step.line || e.Cursor() != len([]rune(step.line)) {
+			t.Fatalf("step %v: got %q with the cursor at %v, want %q at the end", i, e.Line(), e.Cursor(), step.line)
+		}
+	}
+
+	// editing a line from the history submits a new one
+	typeKeys(&e, KeyUp, KeyUp, KeyBackspace, KeyBackspace, "us")
+	if got := e.Submit(); got != "status" {
+		t.Fatalf("submitted %q, want %q", got, "status")
+	}
+	if e.Line() != "" || e.pos != len(e.history) {
+		t.Errorf("got %q at history position %v after submitting, want an empty line at %v", e.Line(), e.pos, len(e.history))
+	}
+}
+
+func TestEditorComplete(t *testing.T) {
+	candidates := func(words []string) []string {
+		if len(words) == 0 {
+			return []string{"move", "spawn", "spam", "status"}
+		}
+		if words[0] == "move" {
+			return []string{"americas", "antarctica", "asia"}
+		}
+		return nil
+	}
+	tests := []struct {
+		name    string
+		keys    []any
+		line    string
+		matches []string
+	}{
+		{"single match", []any{"mo"}, "move ", nil},
+		{"common prefix", []any{"sp"}, "spa", []string{"spawn", "spam"}},
+		{"ambiguous", []any{"s"}, "s", []string{"spawn", "spam", "status"}},
+		{"next word", []any{"move an"}, "move antarctica ", nil},
+		{"no match", []any{"quit"}, "quit", nil},
+		{"nothing to complete", []any{"spawn "}, "spawn ", nil},
+		{"under the cursor", []any{"mo asia", KeyHome, KeyRight, KeyRight}, "move  asia", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e Editor
+			typeKeys(&e, tt.keys...)
+			matches := e.Complete(candidates)
+			if e.Line() != tt.line {
+				t.Errorf("got %q, want %q", e.Line(), tt.line)
+			}
+			if !reflect.DeepEqual(matches, tt.matches) {
+				t.Errorf("got matches %q, want %q", matches, tt.matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package lineedit
+
+import "bufio"
+
+type Key int
+
+const (
+	KeyUnknown Key = iota
+	KeyRune
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyDelete
+	KeyDeleteWord
+	KeyKillLine
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyRedraw
+	// KeyInterrupt is ctrl-c and KeyEOF ctrl-d
+	KeyInterrupt
+	KeyEOF
+)
+
+// ReadKey reads the next key pressed, the arrows and the other special keys
+// come in as escape sequences
+func ReadKey(in *bufio.Reader) (Key, rune, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return KeyUnknown, 0, err
+	}
+	switch r {
+	case '\r', '\n':
+		return KeyEnter, r, nil
+	case '\t':
+		return KeyTab, r, nil
+	case 127, 8:
+		return KeyBackspace, r, nil
+	case 1:
+		return KeyHome, r, nil
+	case 5:
+		return KeyEnd, r, nil
+	case 3:
+		return KeyInterrupt, r, nil
+	case 4:
+		return KeyEOF, r, nil
+	case 12:
+		return KeyRedraw, r, nil
+	case 21:
+		return KeyKillLine, r, nil
+	case 23:
+		return KeyDeleteWord, r, nil
+	case 27:
+		return readEscape(in)
+	}
+	if r < ' ' {
+		return KeyUnknown, r, nil
+	}
+	return KeyRune, r, nil
+}
+
+func readEscape(in *bufio.Reader) (Key, rune, error) {
+	b, err := in.ReadByte()
+	if err != nil {
+		return KeyUnknown, 0, err
+	}
+	if b != '[' && b != 'O' {
+		return KeyUnknown, 0, nil
+	}
+	seq := ""
+	for {
+		c, err := in.ReadByte()
+		if err != nil {
+			return KeyUnknown, 0, err
+		}
+		seq += string(c)
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+	}
+	switch seq {
+	case "A":
+		return KeyUp, 0, nil
+	case "B":
+		return KeyDown, 0, nil
+	case "C":
+		return KeyRight, 0, nil
+	case "D":
+		return KeyLeft, 0, nil
+	case "H", "1~", "7~":
+		return KeyHome, 0, nil
+	case "F", "4~", "8~":
+		return KeyEnd, 0, nil
+	case "3~":
+		return KeyDelete, 0, nil
+	case "5~":
+		return KeyPageUp, 0, nil
+	case "6~":
+		return KeyPageDown, 0, nil
+	}
+	return KeyUnknown, 0, nil
+}
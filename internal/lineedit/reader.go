@@ -0,0 +1,131 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var ErrInterrupted = errors.New("interrupted")
+
+// Reader reads lines from the terminal with editing, history and completion.
+// When the input is not a terminal it reads plain lines.
+type Reader struct {
+	file     *os.File
+	in       *bufio.Reader
+	out      io.Writer
+	editor   Editor
+	complete Completer
+
+	// the terminal is set up by the first ReadLine and stays that way until
+	// Close, mu guards it since Close can come from anywhere
+	mu      sync.Mutex
+	restore func()
+	plain   bool
+}
+
+func NewReader(file *os.File, out io.Writer) *Reader {
+	return &Reader{
+		file: file,
+		in:   bufio.NewReader(file),
+		out:  out,
+	}
+}
+
+func (r *Reader) LoadHistory(path string) error {
+	return r.editor.LoadHistory(path)
+}
+
+func (r *Reader) SetCompleter(c Completer) {
+	r.complete = c
+}
+
+// ReadLine shows the prompt and returns the line typed, io.EOF once the input
+// is over or ctrl-d is pressed and ErrInterrupted for ctrl-c
+func (r *Reader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.takeTerminal() {
+		return r.readPlain()
+	}
+
+	for {
+		k, ch, err := ReadKey(r.in)
+		if err != nil {
+			fmt.Fprintln(r.out)
+			return "", err
+		}
+		switch k {
+		case KeyEnter:
+			fmt.Fprintln(r.out)
+			return r.editor.Submit(), nil
+		case KeyInterrupt:
+			fmt.Fprintln(r.out, "^C")
+			r.editor.Clear()
+			return "", ErrInterrupted
+		case KeyEOF:
+			if r.editor.Empty() {
+				fmt.Fprintln(r.out)
+				return "", io.EOF
+			}
+			r.editor.Delete()
+		case KeyTab:
+			if matches := r.editor.Complete(r.complete); len(matches) > 0 {
+				fmt.Fprintf(r.out, "\n%s\n", strings.Join(matches, "  "))
+			}
+		case KeyRedraw:
+			fmt.Fprint(r.out, "\x1b[H\x1b[2J")
+		default:
+			r.editor.Apply(k, ch)
+		}
+		r.refresh(prompt)
+	}
+}
+
+// takeTerminal reports whether the input is a terminal we can edit lines on
+func (r *Reader) takeTerminal() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.restore == nil && !r.plain {
+		// output processing stays on so whatever is printed between two
+		// lines still starts on a new line
+		restore, err := SetMode(r.file, "-icanon", "-echo", "-isig", "min", "1")
+		if err != nil {
+			r.plain = true
+			return false
+		}
+		r.restore = restore
+	}
+	return r.restore != nil
+}
+
+// Close gives the terminal its settings back, the next ReadLine takes it
+// again
+func (r *Reader) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.restore != nil {
+		r.restore()
+		r.restore = nil
+	}
+}
+
+// refresh redraws the prompt and the line, leaving the cursor where it is in
+// the line
+func (r *Reader) refresh(prompt string) {
+	fmt.Fprintf(r.out, "\r%s%s\x1b[K\r", prompt, r.editor.Line())
+	if col := len([]rune(prompt)) + r.editor.Cursor(); col > 0 {
+		fmt.Fprintf(r.out, "\x1b[%vC", col)
+	}
+}
+
+func (r *Reader) readPlain() (string, error) {
+	line, err := r.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,61 @@
+package lineedit
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// stty changes the settings of the terminal behind f, there is no portable
+// way to do it from the standard library
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// SetMode applies the stty settings to the terminal behind f, the returned
+// func puts the old ones back. It fails when f is not a terminal.
+func SetMode(f *os.File, settings ...string) (func(), error) {
+	saved, err := stty(f, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("error: not a terminal")
+	}
+	_, err = stty(f, settings...)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not set the terminal mode: %v", err)
+	}
+	return func() {
+		stty(f, saved)
+	}, nil
+}
+
+// MakeRaw hands every key to us as soon as it is pressed, without echo and
+// without turning ctrl-c into a signal
+func MakeRaw(f *os.File) (func(), error) {
+	return SetMode(f, "raw", "-echo")
+}
+
+// Size returns the columns and rows of the terminal behind f
+func Size(f *os.File) (int, int, error) {
+	out, err := stty(f, "size")
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("error: unexpected terminal size %q", out)
+	}
+	rows, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	cols, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return cols, rows, nil
+}
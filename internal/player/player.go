@@ -0,0 +1,277 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Hooks let a front end react to what the session does, every one of them is
+// optional
+type Hooks struct {
+	// Logf reports what failed to be published, printed by default
+	Logf func(format string, a ...any)
+	// Seen gets the snapshot of another player who moved or arrived
+	Seen func(p gamelogic.Player)
+	// Arrived is called once our own units reached their destination
+	Arrived func(arrival gamelogic.ArmyArrival)
+	// Fought gets the result of every war we resolved, and of the ones
+	// resolved by someone else that cost us units
+	Fought func(result gamelogic.WarResult)
+	// Seed picks the seed of the wars we declare, rand.Int63 by default
+	Seed func() int64
+}
+
+// Session plays one player of a game over the broker: it publishes the
+// player's orders and statuses and handles what the other players do. The
+// client, the gateway and the bots only differ in how they show the game and
+// who gives the orders.
+type Session struct {
+	gs    *gamelogic.GameState
+	ch    *amqp.Channel
+	hooks Hooks
+}
+
+func New(gs *gamelogic.GameState, ch *amqp.Channel, hooks Hooks) *Session {
+	if hooks.Logf == nil {
+		hooks.Logf = func(format string, a ...any) {
+			fmt.Printf(format+"\n", a...)
+		}
+	}
+	if hooks.Seed == nil {
+		hooks.Seed = rand.Int63
+	}
+	return &Session{gs: gs, ch: ch, hooks: hooks}
+}
+
+func (s *Session) fogOfWar() bool {
+	return s.gs.GetRuleset().FogOfWar
+}
+
+// SubscribeMoves binds the moves and arrivals of the other players. With fog
+// of war the server only forwards the ones we can see.
+func (s *Session) SubscribeMoves(conn *amqp.Connection, onMove func(gamelogic.ArmyMove) pubsub.AckType, onArrival func(gamelogic.ArmyArrival) pubsub.AckType) error {
+	game, name := s.gs.GetGame(), s.gs.GetUsername()
+	movesPrefix, arrivalsPrefix, suffix := routing.ArmyMovesPrefix, routing.ArmyArrivalsPrefix, "*"
+	if s.fogOfWar() {
+		movesPrefix, arrivalsPrefix, suffix = routing.VisibleMovesPrefix, routing.VisibleArrivalsPrefix, name
+	}
+	err := pubsub.SubscribeJSON(conn, routing.ExchangePerilTopic, routing.GameKey(movesPrefix, game, name), routing.GameKey(movesPrefix, game, suffix), pubsub.TransientQueue, onMove)
+	if err != nil {
+		return err
+	}
+	return pubsub.SubscribeJSON(conn, routing.ExchangePerilTopic, routing.GameKey(arrivalsPrefix, game, name), routing.GameKey(arrivalsPrefix, game, suffix), pubsub.TransientQueue, onArrival)
+}
+
+// SubscribeWars binds the wars declared in the game and their results. The war
+// queue is shared by every player, the attacker is the one who resolves it.
+func (s *Session) SubscribeWars(conn *amqp.Connection, onWar func(gamelogic.RecognitionOfWar) pubsub.AckType, onResult func(gamelogic.WarResult) pubsub.AckType) error {
+	game, name := s.gs.GetGame(), s.gs.GetUsername()
+	err := pubsub.SubscribeJSON(conn, routing.ExchangePerilTopic, routing.GameKey(routing.WarResultsPrefix, game, name), routing.GameKey(routing.WarResultsPrefix, game, "*"), pubsub.TransientQueue, onResult)
+	if err != nil {
+		return err
+	}
+	return pubsub.SubscribeJSON(conn, routing.ExchangeWarTopic, routing.GameKey(routing.WarRecognitionsPrefix, game), routing.GameKey(routing.WarRecognitionsPrefix, game, "*"), pubsub.DurableQueue, onWar)
+}
+
+// Order carries out a spawn or move command, or queues it for the end of the
+// turn in a turn-based game
+func (s *Session) Order(words []string) error {
+	if s.gs.IsTurnBased() {
+		return s.gs.QueueOrder(words)
+	}
+	return s.runOrder(words)
+}
+
+func (s *Session) runOrder(words []string) error {
+	switch words[0] {
+	case "spawn":
+		err := s.gs.CommandSpawn(words)
+		if err != nil {
+			return err
+		}
+		s.PublishStatus()
+	case "move":
+		movement, err := s.gs.CommandMove(words)
+		if err != nil {
+			return err
+		}
+		err = pubsub.PublishJSON(s.ch, routing.MovesExchange(s.fogOfWar()), routing.GameKey(routing.ArmyMovesPrefix, s.gs.GetGame(), s.gs.GetUsername()), movement)
+		if err != nil {
+			return errors.New("error: failed to publish the move")
+		}
+		s.PublishStatus()
+		time.AfterFunc(time.Until(movement.ArrivesAt), func() {
+			s.arrive(movement)
+		})
+	}
+	return nil
+}
+
+func (s *Session) arrive(movement gamelogic.ArmyMove) {
+	arrival := s.gs.CompleteMove(movement)
+	err := pubsub.PublishJSON(s.ch, routing.MovesExchange(s.fogOfWar()), routing.GameKey(routing.ArmyArrivalsPrefix, s.gs.GetGame(), s.gs.GetUsername()), arrival)
+	if err != nil {
+		s.hooks.Logf("Failed to publish the arrival in %s", arrival.Location)
+	}
+	s.PublishStatus()
+	if s.hooks.Arrived != nil {
+		s.hooks.Arrived(arrival)
+	}
+}
+
+// PublishStatus lets the server and the allies know about the units of the
+// player after they changed
+func (s *Session) PublishStatus() {
+	err := pubsub.PublishJSON(s.ch, routing.ExchangePerilTopic, routing.GameKey(routing.PlayerStatusPrefix, s.gs.GetGame(), s.gs.GetUsername()), gamelogic.PlayerStatus{
+		Game:   s.gs.GetGame(),
+		Player: s.gs.GetPlayerSnap(),
+		Funds:  s.gs.GetFunds(),
+	})
+	if err != nil {
+		s.hooks.Logf("Failed to publish status")
+	}
+	for _, msg := range s.gs.AllyStatuses() {
+		err = pubsub.PublishJSON(s.ch, routing.ExchangePerilDiplomacy, routing.GameKey(routing.DiplomacyPrefix, msg.Game, msg.To), msg)
+		if err != nil {
+			s.hooks.Logf("Failed to send status to %s", msg.To)
+		}
+	}
+}
+
+// PublishWar declares a separate war for every location the players share, so
+// each battle is resolved and logged on its own
+func (s *Session) PublishWar(defender gamelogic.Player) {
+	attacker := s.gs.GetPlayerSnap()
+	for _, loc := range s.gs.WarLocations(defender) {
+		rw := gamelogic.RecognitionOfWar{
+			Game:           s.gs.GetGame(),
+			Attacker:       attacker,
+			AttackerAllies: s.gs.GetAllySnaps(),
+			Defender:       defender,
+			Location:       loc,
+			Combat:         s.gs.GetRuleset().Combat,
+			Seed:           s.hooks.Seed(),
+		}
+		if s.fogOfWar() {
+			rw = gamelogic.StripWar(rw)
+		}
+		err := pubsub.PublishJSON(s.ch, routing.ExchangeWarTopic, routing.GameKey(routing.WarRecognitionsPrefix, s.gs.GetGame(), attacker.Username), rw)
+		if err != nil {
+			s.hooks.Logf("Failed to publish war in %s", loc)
+		}
+	}
+}
+
+// PublishGameLog sends a line to the server's game log, muted players can't
+func (s *Session) PublishGameLog(message string) error {
+	if s.gs.IsMuted() {
+		return nil
+	}
+	return pubsub.PublishGob(s.ch, routing.ExchangeGameLogs, routing.GameKey(routing.GameLogSlug, s.gs.GetGame(), s.gs.GetUsername()), routing.GameLog{
+		Username:    s.gs.GetUsername(),
+		Message:     message,
+		CurrentTime: time.Now(),
+		Game:        s.gs.GetGame(),
+	})
+}
+
+func (s *Session) HandleMove(move gamelogic.ArmyMove) pubsub.AckType {
+	return s.handleOutcome(s.gs.HandleMove(move), move.Player)
+}
+
+func (s *Session) HandleArrival(arrival gamelogic.ArmyArrival) pubsub.AckType {
+	return s.handleOutcome(s.gs.HandleArrival(arrival), arrival.Player)
+}
+
+func (s *Session) handleOutcome(outcome gamelogic.MoveOutcome, other gamelogic.Player) pubsub.AckType {
+	switch outcome {
+	case gamelogic.MoveOutComeSafe:
+		s.seen(other)
+		return pubsub.Ack
+	case gamelogic.MoveOutcomeMakeWar:
+		s.seen(other)
+		s.PublishWar(other)
+		return pubsub.Ack
+	default:
+		return pubsub.NackDiscard
+	}
+}
+
+func (s *Session) seen(p gamelogic.Player) {
+	if s.hooks.Seen != nil {
+		s.hooks.Seen(p)
+	}
+}
+
+// HandleWar resolves the wars we declared. The others are requeued for the
+// player who did.
+func (s *Session) HandleWar(rw gamelogic.RecognitionOfWar) pubsub.AckType {
+	outcome, result := s.gs.HandleWar(rw)
+	var message string
+	switch outcome {
+	case gamelogic.WarOutcomeNotInvolved:
+		return pubsub.NackRequeue
+	case gamelogic.WarOutcomeNoUnits:
+		return pubsub.NackDiscard
+	case gamelogic.WarOutcomeYouWon, gamelogic.WarOutcomeOpponentWon:
+		message = fmt.Sprintf("%s won a war against %s in %s", result.Winner, result.Loser, result.Location)
+	case gamelogic.WarOutcomeDraw:
+		message = fmt.Sprintf("A war between %s and %s in %s resulted in a draw", result.Winner, result.Loser, result.Location)
+	default:
+		s.hooks.Logf("Unknown war outcome")
+		return pubsub.NackDiscard
+	}
+
+	s.PublishStatus()
+	err := pubsub.PublishJSON(s.ch, routing.ExchangePerilTopic, routing.GameKey(routing.WarResultsPrefix, s.gs.GetGame(), s.gs.GetUsername()), result)
+	if err != nil {
+		s.hooks.Logf("Failed to publish war result")
+		return pubsub.NackRequeue
+	}
+	s.fought(result)
+	// the war is over once its result is out, requeueing it now would fight
+	// it again
+	err = s.PublishGameLog(message)
+	if err != nil {
+		s.hooks.Logf("Failed to publish game log")
+	}
+	return pubsub.Ack
+}
+
+// HandleWarResult removes our units killed in wars resolved by other players
+func (s *Session) HandleWarResult(result gamelogic.WarResult) pubsub.AckType {
+	if s.gs.HandleWarResult(result) {
+		s.PublishStatus()
+		s.fought(result)
+	}
+	return pubsub.Ack
+}
+
+func (s *Session) fought(result gamelogic.WarResult) {
+	if s.hooks.Fought != nil {
+		s.hooks.Fought(result)
+	}
+}
+
+func (s *Session) HandleIncome(tick gamelogic.IncomeTick) pubsub.AckType {
+	s.gs.HandleIncome(tick)
+	s.PublishStatus()
+	return pubsub.Ack
+}
+
+// HandleTurnEnd carries out the orders queued during the turn
+func (s *Session) HandleTurnEnd(te routing.TurnEnd) pubsub.AckType {
+	for _, order := range s.gs.HandleTurnEnd(te) {
+		if err := s.runOrder(order); err != nil {
+			s.hooks.Logf("%v", err)
+		}
+	}
+	return pubsub.Ack
+}
@@ -12,6 +12,13 @@ import (
 const DurableQueue = 0
 const TransientQueue = 1
 
+// QueuePrefix is put in front of every queue we declare, so several
+// deployments can share a broker
+var QueuePrefix = ""
+
+// DeadLetterExchange gets the messages our handlers discard
+var DeadLetterExchange = "peril_dlx"
+
 type AckType string
 
 const (
@@ -95,7 +102,20 @@ func SubscribeJSON[T any](conn *amqp.Connection, exchange, queueName, key string
 	return nil
 }
 
+// DeclareExchange creates the exchanges that are not set up in the management
+// UI, declaring an exchange that already exists does nothing
+func DeclareExchange(conn *amqp.Connection, exchange, kind string) error {
+	channel, err := conn.Channel()
+	if err != nil {
+		fmt.Println("Failed to open a channel")
+		return err
+	}
+	defer channel.Close()
+	return channel.ExchangeDeclare(exchange, kind, true, false, false, false, nil)
+}
+
 func DeclareAndBindNotDLQ(conn *amqp.Connection, exchange, queueName, key string, simpleQueueType int) (*amqp.Channel, amqp.Queue, error) {
+	queueName = QueuePrefix + queueName
 
 	channel, err := conn.Channel()
 	if err != nil {
@@ -181,6 +201,7 @@ func SubscribeGeneric[T any](conn *amqp.Connection, exchange, queueName, key str
 }
 
 func DeclareAndBind(conn *amqp.Connection, exchange, queueName, key string, simpleQueueType int) (*amqp.Channel, amqp.Queue, error) {
+	queueName = QueuePrefix + queueName
 
 	channel, err := conn.Channel()
 	if err != nil {
@@ -192,7 +213,7 @@ func DeclareAndBind(conn *amqp.Connection, exchange, queueName, key string, simp
 
 	var table amqp.Table
 	table = amqp.Table{
-		"x-dead-letter-exchange": "peril_dlx",
+		"x-dead-letter-exchange": DeadLetterExchange,
 	}
 
 	switch simpleQueueType {
@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DirectReplyTo is RabbitMQ's pseudo-queue for replies, it saves declaring a
+// queue for every request
+const DirectReplyTo = "amq.rabbitmq.reply-to"
+
+var ErrRequestTimeout = errors.New("request timed out")
+
+func ServeJSON[Req any, Resp any](conn *amqp.Connection, exchange, queueName, key string, handler func(Req) Resp) error {
+
+	channel, queue, err := DeclareAndBind(conn, exchange, queueName, key, DurableQueue)
+	if err != nil {
+		fmt.Println("Failed to declare and bind queue")
+		return err
+	}
+
+	requests, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		fmt.Println("Failed to consume the queue")
+		return err
+	}
+
+	go func() {
+		for msg := range requests {
+			var req Req
+			err := json.Unmarshal(msg.Body, &req)
+			if err != nil {
+				fmt.Println("Failed to unmarshal the request")
+				msg.Nack(false, false)
+				continue
+			}
+			body, err := json.Marshal(handler(req))
+			if err != nil {
+				fmt.Println("Failed to marshal the response")
+				msg.Nack(false, false)
+				continue
+			}
+			if msg.ReplyTo != "" {
+				err = channel.PublishWithContext(context.Background(), "", msg.ReplyTo, false, false, amqp.Publishing{
+					ContentType:   "application/json",
+					CorrelationId: msg.CorrelationId,
+					Body:          body,
+				})
+				if err != nil {
+					fmt.Println("Failed to publish the response")
+				}
+			}
+			msg.Ack(false)
+		}
+	}()
+
+	return nil
+}
+
+func RequestJSON[Req any, Resp any](conn *amqp.Connection, exchange, key string, req Req, timeout time.Duration) (Resp, error) {
+	var resp Resp
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return resp, err
+	}
+	defer channel.Close()
+
+	replies, err := channel.Consume(DirectReplyTo, "", true, false, false, false, nil)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	correlationID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	err = channel.PublishWithContext(context.Background(), exchange, key, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: correlationID,
+		ReplyTo:       DirectReplyTo,
+		// nobody will be waiting for the answer after the timeout
+		Expiration: strconv.FormatInt(timeout.Milliseconds(), 10),
+		Body:       body,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case msg, ok := <-replies:
+			if !ok {
+				return resp, errors.New("reply channel closed")
+			}
+			if msg.CorrelationId != correlationID {
+				continue
+			}
+			err = json.Unmarshal(msg.Body, &resp)
+			return resp, err
+		case <-timer.C:
+			return resp, ErrRequestTimeout
+		}
+	}
+}
@@ -4,10 +4,84 @@ import "time"
 
 type PlayingState struct {
 	IsPaused bool
+	// Turn and Deadline are only set in turn-based games
+	Turn     int
+	Deadline time.Time
+	// Reason and ResumeAt explain a pause, ResumeAt is zero when nobody knows
+	// when the game will resume
+	Reason   string
+	ResumeAt time.Time
+	// Version goes up with every change so clients can drop stale states
+	Version int
+}
+
+type StateRequest struct {
+	Game string
+}
+
+type StateResponse struct {
+	State PlayingState
+	Error string
+}
+
+type TurnStart struct {
+	Game     string
+	Turn     int
+	Deadline time.Time
+}
+
+type TurnEnd struct {
+	Game string
+	Turn int
 }
 
 type GameLog struct {
 	CurrentTime time.Time
 	Message     string
 	Username    string
+	Game        string
+}
+
+type GameInfo struct {
+	ID        string
+	Players   []string
+	CreatedAt time.Time
+	TurnBased bool
+}
+
+type JoinRequest struct {
+	Game     string
+	Username string
+}
+
+type JoinResponse struct {
+	Game  GameInfo
+	Muted bool
+	// Banned players are refused by every game on the server
+	Banned bool
+	Error  string
+}
+
+type ControlAction string
+
+const (
+	ControlKick   ControlAction = "kick"
+	ControlBan    ControlAction = "ban"
+	ControlUnban  ControlAction = "unban"
+	ControlMute   ControlAction = "mute"
+	ControlUnmute ControlAction = "unmute"
+)
+
+// ControlMessage is sent by the server to a single player, who must obey it
+type ControlMessage struct {
+	Action ControlAction
+	Target string
+	Reason string
+	// Until is when a ban ends, it's zero for permanent bans
+	Until time.Time
+}
+
+type GameClosed struct {
+	Game   string
+	Reason string
 }
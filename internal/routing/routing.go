@@ -1,17 +1,116 @@
 package routing
 
+import (
+	"strings"
+	"time"
+)
+
 const (
 	ArmyMovesPrefix = "army_moves"
 
+	ArmyArrivalsPrefix = "army_arrivals"
+
 	WarRecognitionsPrefix = "war"
 
 	PauseKey = "pause"
 
 	GameLogSlug = "game_logs"
+
+	GameClosedPrefix = "game_closed"
+
+	GameOverPrefix = "game_over"
+
+	PlayerStatusPrefix = "player_status"
+
+	WarResultsPrefix = "war_results"
+
+	IncomePrefix = "income"
+
+	TurnStartPrefix = "turn_start"
+
+	TurnEndPrefix = "turn_end"
+
+	VisibleMovesPrefix = "visible_moves"
+
+	VisibleArrivalsPrefix = "visible_arrivals"
+
+	DiplomacyPrefix = "diplomacy"
+
+	ChatPrefix = "chat"
+
+	ControlPrefix = "control"
 )
 
 const (
+	RPCRulesetKey = "rpc.ruleset"
+	RPCGamesKey   = "rpc.games"
+	RPCJoinKey    = "rpc.join"
+	RPCStatsKey   = "rpc.stats"
+	RPCHistoryKey = "rpc.history"
+	RPCStateKey   = "rpc.state"
+)
+
+const RPCTimeout = 5 * time.Second
+
+// the exchange names are variables so a deployment can prefix them, see
+// SetExchangePrefix
+var (
 	ExchangePerilDirect = "peril_direct"
 	ExchangePerilTopic  = "peril_topic"
 	ExchangeWarTopic    = "war_topic"
+	ExchangeGameLogs    = GameLogSlug
+	ExchangeDeadLetter  = "peril_dlx"
+
+	ExchangePerilDiplomacy = "peril_diplomacy"
+	ExchangePerilChat      = "peril_chat"
+	// ExchangePerilFog gets the raw moves and arrivals of fog of war games,
+	// only the server reads them
+	ExchangePerilFog = "peril_fog"
 )
+
+type Exchange struct {
+	Name string
+	Kind string
+}
+
+// SetExchangePrefix puts prefix in front of every exchange name
+func SetExchangePrefix(prefix string) {
+	ExchangePerilDirect = prefix + "peril_direct"
+	ExchangePerilTopic = prefix + "peril_topic"
+	ExchangeWarTopic = prefix + "war_topic"
+	ExchangeGameLogs = prefix + GameLogSlug
+	ExchangeDeadLetter = prefix + "peril_dlx"
+	ExchangePerilDiplomacy = prefix + "peril_diplomacy"
+	ExchangePerilChat = prefix + "peril_chat"
+	ExchangePerilFog = prefix + "peril_fog"
+}
+
+// Exchanges lists every exchange Peril publishes to, so the server can
+// declare them when they were not set up by hand
+func Exchanges() []Exchange {
+	return []Exchange{
+		{ExchangePerilDirect, "direct"},
+		{ExchangePerilTopic, "topic"},
+		{ExchangeWarTopic, "topic"},
+		{ExchangeGameLogs, "topic"},
+		{ExchangeDeadLetter, "fanout"},
+		{ExchangePerilDiplomacy, "topic"},
+		{ExchangePerilChat, "topic"},
+		{ExchangePerilFog, "topic"},
+	}
+}
+
+// MovesExchange is where players publish their moves and arrivals. With fog
+// of war they go to the server, which forwards what every player can see.
+func MovesExchange(fogOfWar bool) string {
+	if fogOfWar {
+		return ExchangePerilFog
+	}
+	return ExchangePerilTopic
+}
+
+// GameKey scopes a routing key or queue name to a single game, so several
+// matches can share the same exchanges, e.g. army_moves.<game>.<player>
+func GameKey(prefix, game string, parts ...string) string {
+	return strings.Join(append([]string{prefix, game}, parts...), ".")
+}